@@ -0,0 +1,154 @@
+package fw_openapi
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/linxlib/astp/constants"
+	"github.com/linxlib/astp/types"
+	"github.com/pterm/pterm"
+	spec "github.com/sv-tools/openapi"
+)
+
+// structField 结构体序列化后的一个字段, 嵌入结构体的字段按encoding/json的规则提升
+type structField struct {
+	name     string
+	depth    int  // 嵌入的层数, 结构体自身的字段为0
+	tagged   bool // 标签中指定了名称
+	required bool
+	schema   *spec.RefOrSpec[spec.Schema]
+}
+
+// tagFieldName
+// 标签中的字段名, json:",omitempty" 这样没有名称的返回空.
+// xml标签 "namespace name" 取name, "items>item" 取第一层元素, 嵌套由 applyXMLTag 的xml对象表示
+func tagFieldName(tag reflect.StructTag, tagName string) string {
+	name, _, _ := strings.Cut(tag.Get(tagName), ",")
+	if tagName == "xml" {
+		if i := strings.LastIndex(name, " "); i >= 0 {
+			name = name[i+1:]
+		}
+		name, _, _ = strings.Cut(name, ">")
+	}
+	return name
+}
+
+// isEmbeddedField 匿名嵌入的字段(Base / *Base), 泛型的嵌入作为普通字段处理
+func isEmbeddedField(f *types.Field) bool {
+	return f.Name == constants.EmptyName || (f.Parent && !f.Generic)
+}
+
+// embeddedName 匿名嵌入的非结构体字段使用类型名作为字段名
+func embeddedName(f *types.Field) string {
+	if f.Name != constants.EmptyName && f.Name != "" {
+		return f.Name
+	}
+	t := strings.TrimLeft(f.Type, "*")
+	t, _, _ = strings.Cut(t, "[")
+	if i := strings.LastIndex(t, "."); i >= 0 {
+		t = t[i+1:]
+	}
+	return t
+}
+
+// collectFields
+// 收集结构体的字段, 与encoding/json一致:
+// 没有标签名称的嵌入结构体的字段被提升到外层, 带标签名称的嵌入结构体及嵌入的非结构体类型作为普通字段;
+// 通过指针嵌入的结构体可能为nil, 提升的字段不再必填.
+// embeds不为nil时, 直接嵌入的具名结构体不展开, 而是以$ref记录到embeds中(用于allOf)
+func (oa *OpenAPI) collectFields(s *types.Struct, tagName string, depth int, optional bool, fields *[]*structField, embeds *[]*spec.RefOrSpec[spec.Schema]) {
+	s.VisitFields(func(element *types.Field) bool {
+		return !element.Private
+	}, func(field *types.Field) {
+		tag := field.GetTag()
+		// XMLName 只用于指定xml根元素的名称
+		if tag.Get(tagName) == "-" || field.Name == "XMLName" {
+			return
+		}
+		name := tagFieldName(tag, tagName)
+		if isEmbeddedField(field) && name == "" && field.Struct != nil && !field.Struct.IsEnum() && isStructType(field.Struct) {
+			if embeds != nil && depth == 0 && tagName == "json" && !isAnonymousStruct(field.Struct) {
+				*embeds = append(*embeds, oa.structSchema(field.Struct, tagName))
+				return
+			}
+			// 嵌入自身(type Node struct{ *Node })时不再展开
+			structName, ok := oa.enterStruct(field.Struct)
+			if !ok {
+				return
+			}
+			oa.collectFields(field.Struct, tagName, depth+1, optional || isPointerField(field), fields, nil)
+			oa.leaveStruct(structName)
+			return
+		}
+		tagged := name != ""
+		if !tagged {
+			if isEmbeddedField(field) {
+				name = embeddedName(field)
+			} else {
+				name = field.Name
+			}
+		}
+
+		defaultValue := ""
+		defaultValue = oa.getTagByName(tag, defaultValue, "default")
+		comment := oa.getComment(field.Comment)
+		example := ""
+		example = oa.getTagByName(tag, example, "example")
+		schema := oa.NewFieldProp(field, tagName, defaultValue, comment, example)
+		rules := parseValidateRules(tag)
+		applyValidateRules(schema, rules)
+		if tagName == "json" || tagName == "xml" {
+			schema = applyXMLTag(schema, tag, name)
+		}
+		*fields = append(*fields, &structField{
+			name:     name,
+			depth:    depth,
+			tagged:   tagged,
+			required: !optional && hasRequiredRule(rules) && !isOmitEmpty(tag, tagName),
+			schema:   schema,
+		})
+	})
+}
+
+// dominantFields
+// 同名字段按encoding/json的规则取舍: 嵌入层数少的优先, 层数相同时带标签名称的唯一字段优先,
+// 仍无法区分的字段被丢弃
+func dominantFields(structName string, fields []*structField) []*structField {
+	names := make([]string, 0)
+	byName := make(map[string][]*structField)
+	for _, f := range fields {
+		if _, ok := byName[f.name]; !ok {
+			names = append(names, f.name)
+		}
+		byName[f.name] = append(byName[f.name], f)
+	}
+	result := make([]*structField, 0, len(names))
+	for _, name := range names {
+		candidates := byName[name]
+		depth := candidates[0].depth
+		for _, f := range candidates[1:] {
+			if f.depth < depth {
+				depth = f.depth
+			}
+		}
+		var dominant, tagged []*structField
+		for _, f := range candidates {
+			if f.depth != depth {
+				continue
+			}
+			dominant = append(dominant, f)
+			if f.tagged {
+				tagged = append(tagged, f)
+			}
+		}
+		switch {
+		case len(dominant) == 1:
+			result = append(result, dominant[0])
+		case len(tagged) == 1:
+			result = append(result, tagged[0])
+		default:
+			pterm.Warning.Printfln("openapi: field %s of %s is ambiguous between embedded structs, ignored", name, structName)
+		}
+	}
+	return result
+}
@@ -0,0 +1,147 @@
+package fw_openapi
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/linxlib/astp/types"
+)
+
+func TestDominantFields(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields []*structField
+		want   []string
+	}{
+		{
+			name: "distinct names keep their order",
+			fields: []*structField{
+				{name: "id"},
+				{name: "name"},
+				{name: "created_at", depth: 1},
+			},
+			want: []string{"id", "name", "created_at"},
+		},
+		{
+			name: "shallower field wins",
+			fields: []*structField{
+				{name: "id", depth: 1},
+				{name: "id"},
+			},
+			want: []string{"id@0"},
+		},
+		{
+			name: "tagged field wins at the same depth",
+			fields: []*structField{
+				{name: "id", depth: 1},
+				{name: "id", depth: 1, tagged: true},
+			},
+			want: []string{"id@1t"},
+		},
+		{
+			name: "two untagged fields at the same depth are dropped",
+			fields: []*structField{
+				{name: "name"},
+				{name: "id", depth: 1},
+				{name: "id", depth: 1},
+			},
+			want: []string{"name"},
+		},
+		{
+			name: "two tagged fields at the same depth are dropped",
+			fields: []*structField{
+				{name: "id", depth: 1, tagged: true},
+				{name: "id", depth: 1, tagged: true},
+			},
+			want: []string{},
+		},
+		{
+			name: "a deeper tagged field does not win",
+			fields: []*structField{
+				{name: "id", depth: 2, tagged: true},
+				{name: "id", depth: 1},
+			},
+			want: []string{"id@1"},
+		},
+		{
+			name: "ambiguity at a deeper level is hidden by a shallower field",
+			fields: []*structField{
+				{name: "id", depth: 2},
+				{name: "id", depth: 2},
+				{name: "id", depth: 1},
+			},
+			want: []string{"id@1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make([]string, 0)
+			for _, f := range dominantFields("T", tt.fields) {
+				got = append(got, fieldKey(f, tt.fields))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("dominantFields() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// fieldKey 同名字段以 name@depth(t) 区分, 名称唯一时只用名称
+func fieldKey(f *structField, fields []*structField) string {
+	count := 0
+	for _, other := range fields {
+		if other.name == f.name {
+			count++
+		}
+	}
+	if count == 1 {
+		return f.name
+	}
+	key := fmt.Sprintf("%s@%d", f.name, f.depth)
+	if f.tagged {
+		key += "t"
+	}
+	return key
+}
+
+func TestIsStructType(t *testing.T) {
+	tests := []struct {
+		name string
+		s    *types.Struct
+		want bool
+	}{
+		{name: "struct without underlying type", s: &types.Struct{Name: "Base", TypeName: "Base"}, want: true},
+		{name: "struct reporting itself", s: &types.Struct{Name: "Base", TypeName: "Base", Type: "Base"}, want: true},
+		{name: "anonymous struct", s: &types.Struct{TypeName: "struct{}", Type: "struct{}"}, want: true},
+		{name: "named string", s: &types.Struct{Name: "Name", TypeName: "Name", Type: "string"}},
+		{name: "named int", s: &types.Struct{Name: "UserID", TypeName: "UserID", Type: "int64"}},
+		{name: "named slice", s: &types.Struct{Name: "Tags", TypeName: "Tags", Type: "[]string"}},
+		{name: "named map", s: &types.Struct{Name: "Labels", TypeName: "Labels", Type: "map[string]string"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isStructType(tt.s); got != tt.want {
+				t.Errorf("isStructType(%+v) = %v, want %v", tt.s, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEmbeddedName(t *testing.T) {
+	tests := []struct {
+		field *types.Field
+		want  string
+	}{
+		{field: &types.Field{Name: "Base", Type: "Base", Parent: true}, want: "Base"},
+		{field: &types.Field{Type: "*model.Base", Parent: true}, want: "Base"},
+		{field: &types.Field{Type: "Page[User]", Parent: true}, want: "Page"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := embeddedName(tt.field); got != tt.want {
+				t.Errorf("embeddedName(%q) = %q, want %q", tt.field.Type, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package fw_openapi
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/linxlib/astp/types"
+	"github.com/linxlib/conv"
+	spec "github.com/sv-tools/openapi"
+)
+
+// enumSchema
+// 枚举类型生成对应JSON类型的schema, 常量名及注释写入 x-enum-varnames / x-enum-descriptions 供代码生成器使用
+//
+//	// Status 状态
+//	type Status int
+//	const (
+//		StatusActive Status = iota + 1 // 启用
+//		StatusBanned                   // 禁用
+//	)
+//
+//	=> {type: integer, enum: [1, 2], x-enum-varnames: [StatusActive, StatusBanned], x-enum-descriptions: [启用, 禁用]}
+func (oa *OpenAPI) enumSchema(s *types.Struct) *spec.RefOrSpec[spec.Schema] {
+	typ, format := enumType(s)
+	builder := spec.NewSchemaBuilder().Type(typ).Format(format).Description(oa.getComment(s.Doc))
+	names := make([]string, 0, len(s.Enum.Enums))
+	descriptions := make([]string, 0, len(s.Enum.Enums))
+	hasDescription := false
+	enumDesc := make([]*types.Comment, 0, len(s.Enum.Enums))
+	for _, enum := range s.Enum.Enums {
+		builder.AddEnum(enumValue(typ, enum.Value))
+		names = append(names, enum.Name)
+		comment := oa.getComment(enum.Comment)
+		descriptions = append(descriptions, comment)
+		if comment != "" {
+			hasDescription = true
+			comment = fmt.Sprintf("(%s)", comment)
+		}
+		// 参数(query/form等)没有组件可引用, 其描述中仍列出枚举值
+		enumDesc = append(enumDesc, &types.Comment{
+			Content: fmt.Sprintf("- %s: %s%s\n", conv.String(enum.Value), enum.Name, comment),
+		})
+	}
+	s.Enum.Comment = enumDesc
+	schema := builder.Build()
+	schema.Spec.AddExt("x-enum-varnames", names)
+	if hasDescription {
+		schema.Spec.AddExt("x-enum-descriptions", descriptions)
+	}
+	return schema
+}
+
+// enumType 枚举的JSON类型, 优先按底层类型, 底层类型未知时按枚举值推断
+func enumType(s *types.Struct) (string, string) {
+	if b := primitiveSchema(s.Type); b != nil {
+		schema := b.Build().Spec
+		if typ := schemaType(schema); typ != "" {
+			return typ, schema.Format
+		}
+	}
+	if len(s.Enum.Enums) > 0 {
+		switch s.Enum.Enums[0].Value.(type) {
+		case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+			return "integer", ""
+		case float32, float64:
+			return "number", ""
+		case bool:
+			return "boolean", ""
+		}
+	}
+	return "string", ""
+}
+
+// enumValue 枚举值转换为与JSON类型一致的值
+func enumValue(typ string, value any) any {
+	switch typ {
+	case "integer":
+		return conv.Int64(value)
+	case "number":
+		return conv.Float64(value)
+	case "boolean":
+		return conv.Bool(value)
+	default:
+		return strings.Trim(conv.String(value), "\"`")
+	}
+}
@@ -0,0 +1,174 @@
+package fw_openapi
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pterm/pterm"
+	spec "github.com/sv-tools/openapi"
+)
+
+// exampleValue
+// example标签的值: string类型的字段原样使用, 其他类型按JSON解析(对象、数组、数字等), 不是合法JSON时作为字符串
+func exampleValue(schema *spec.RefOrSpec[spec.Schema], value string) any {
+	if schema != nil && schema.Spec != nil && schemaType(schema.Spec) == "string" {
+		return value
+	}
+	var v any
+	if err := json.Unmarshal([]byte(value), &v); err != nil {
+		return value
+	}
+	return v
+}
+
+// withExample
+// 为字段的schema设置example标签中的示例, $ref 不能附加其他关键字, 使用 allOf: [$ref] 包装
+func withExample(schema *spec.RefOrSpec[spec.Schema], value string) *spec.RefOrSpec[spec.Schema] {
+	if value == "" || schema == nil {
+		return schema
+	}
+	example := exampleValue(schema, value)
+	if schema.Spec != nil {
+		schema.Spec.Example = example
+		return schema
+	}
+	return spec.NewSchemaBuilder().AllOf(schema).Example(example).Build()
+}
+
+// exampleAttr 方法上的 @Example 属性
+//
+//	@Example created docs/examples/user.json      请求body的示例, 没有请求body时为成功响应的示例
+//	@Example 404 notFound docs/examples/404.json  指定状态码的响应示例
+type exampleAttr struct {
+	code string
+	name string
+	file string
+}
+
+// parseExampleAttr 解析 @Example 的值, 格式为 [code] <name> <file.json>
+func parseExampleAttr(value string) (*exampleAttr, bool) {
+	fields := strings.Fields(value)
+	ea := new(exampleAttr)
+	if len(fields) == 3 {
+		ea.code = strings.ToUpper(fields[0])
+		if ea.code == "DEFAULT" {
+			ea.code = "default"
+		}
+		if !statusCodePattern.MatchString(ea.code) {
+			return nil, false
+		}
+		fields = fields[1:]
+	}
+	if len(fields) != 2 {
+		return nil, false
+	}
+	ea.name, ea.file = fields[0], fields[1]
+	return ea, true
+}
+
+// addExample 读取示例文件, 添加到请求body或响应的每个媒体类型的examples中
+func (oa *OpenAPI) addExample(op *spec.Operation, responses map[string]*spec.RefOrSpec[spec.Extendable[spec.Response]], ea *exampleAttr) {
+	data, err := os.ReadFile(ea.file)
+	if err != nil {
+		pterm.Warning.Printfln("openapi: %s can not read example file %s: %s", op.OperationID, ea.file, err)
+		return
+	}
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		pterm.Warning.Printfln("openapi: %s example file %s is not valid JSON: %s", op.OperationID, ea.file, err)
+		return
+	}
+	var content map[string]*spec.Extendable[spec.MediaType]
+	switch {
+	case ea.code == "default" && op.Responses != nil && op.Responses.Spec.Default != nil:
+		content = responseContent(op.Responses.Spec.Default)
+	case ea.code != "":
+		content = responseContent(responses[ea.code])
+	case op.RequestBody != nil && op.RequestBody.Spec != nil:
+		content = op.RequestBody.Spec.Spec.Content
+	default:
+		codes := make([]string, 0, len(responses))
+		for code := range responses {
+			if isSuccessCode(code) {
+				codes = append(codes, code)
+			}
+		}
+		sort.Strings(codes)
+		if len(codes) > 0 {
+			content = responseContent(responses[codes[0]])
+		}
+	}
+	if len(content) == 0 {
+		pterm.Warning.Printfln("openapi: %s has no body for example %s", op.OperationID, ea.name)
+		return
+	}
+	if oa.exampleGroups == nil {
+		oa.exampleGroups = make(map[string]bool)
+	}
+	oa.exampleGroups[oa.currentGroup] = true
+	example := spec.NewExampleBuilder().Summary(ea.name).Value(value).Build()
+	for _, mediaType := range content {
+		if mediaType.Spec.Examples == nil {
+			mediaType.Spec.Examples = make(map[string]*spec.RefOrSpec[spec.Extendable[spec.Example]])
+		}
+		mediaType.Spec.Examples[ea.name] = example
+	}
+}
+
+func responseContent(response *spec.RefOrSpec[spec.Extendable[spec.Response]]) map[string]*spec.Extendable[spec.MediaType] {
+	if response == nil || response.Spec == nil {
+		return nil
+	}
+	return response.Spec.Spec.Content
+}
+
+// validateExamples 按生成的schema校验 @Example 加载的示例, 不符合时输出警告
+func validateExamples(groupName string, g *spec.OpenAPIBuilder) {
+	validator, err := spec.NewValidator(g.Build())
+	if err != nil {
+		pterm.Warning.Printfln("openapi: [%s] examples can not be validated: %s", groupName, err)
+		return
+	}
+	forEachOperation(g, func(route string, method string, op *spec.Operation) {
+		location := "#/paths/" + jsonPointerEscape(route) + "/" + strings.ToLower(method)
+		if op.RequestBody != nil && op.RequestBody.Spec != nil {
+			validateContent(validator, op.OperationID, location+"/requestBody/content", op.RequestBody.Spec.Spec.Content)
+		}
+		if op.Responses == nil {
+			return
+		}
+		if op.Responses.Spec.Default != nil {
+			validateContent(validator, op.OperationID, location+"/responses/default/content", responseContent(op.Responses.Spec.Default))
+		}
+		for code, response := range op.Responses.Spec.Response {
+			validateContent(validator, op.OperationID, location+"/responses/"+code+"/content", responseContent(response))
+		}
+	})
+}
+
+func validateContent(validator *spec.Validator, operationID string, location string, content map[string]*spec.Extendable[spec.MediaType]) {
+	for contentType, mediaType := range content {
+		if len(mediaType.Spec.Examples) == 0 || mediaType.Spec.Schema == nil {
+			continue
+		}
+		schemaLocation := location + "/" + jsonPointerEscape(contentType) + "/schema"
+		if mediaType.Spec.Schema.Ref != nil {
+			schemaLocation = mediaType.Spec.Schema.Ref.Ref
+		}
+		for name, example := range mediaType.Spec.Examples {
+			if example.Spec == nil || example.Spec.Spec.Value == nil {
+				continue
+			}
+			if err := validator.ValidateData(schemaLocation, example.Spec.Spec.Value); err != nil {
+				pterm.Warning.Printfln("openapi: %s example %s does not match the schema: %s", operationID, name, err)
+			}
+		}
+	}
+}
+
+// jsonPointerEscape JSON Pointer中的 ~ 和 / 需要转义
+func jsonPointerEscape(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "~", "~0"), "/", "~1")
+}
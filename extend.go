@@ -1,10 +1,12 @@
 package fw_openapi
 
 import (
-	"fmt"
+	"bytes"
+	"encoding/json"
 	"github.com/linxlib/astp/constants"
 	"github.com/linxlib/astp/types"
 	"github.com/linxlib/conv"
+	"github.com/pterm/pterm"
 	spec "github.com/sv-tools/openapi"
 	"reflect"
 	"strings"
@@ -28,37 +30,34 @@ func (oa *OpenAPI) getComment(comments []*types.Comment) string {
 }
 
 func (oa *OpenAPI) NewParentFieldProp(f *types.Struct, tagName string) map[string]*spec.RefOrSpec[spec.Schema] {
+	fields, _ := oa.newParentFieldProp(f, tagName)
+	return fields
+}
+
+// newParentFieldProp 返回嵌入结构体(含多层嵌入)的字段及其中必填的字段名
+func (oa *OpenAPI) newParentFieldProp(f *types.Struct, tagName string) (map[string]*spec.RefOrSpec[spec.Schema], []string) {
 	fields := make(map[string]*spec.RefOrSpec[spec.Schema])
-	f.VisitFields(func(element *types.Field) bool {
-		return !element.Private
-	}, func(field *types.Field) {
-		fieldName := field.Name
-		fieldName = oa.getTagByName(field.GetTag(), fieldName, tagName)
-		//TODO: 考虑多层嵌套的情况
-		// 处理隐式导入的字段
-		if fieldName == "-" || field.Name == constants.EmptyName {
-			if field.Name == constants.EmptyName {
-				fields1 := oa.NewParentFieldProp(field.Struct, tagName)
-				for s, s2 := range fields1 {
-					fields[s] = s2
-				}
-				return
-			}
-			return
+	required := make([]string, 0)
+	// 嵌入自身(type Node struct{ *Node })时不再展开
+	name, ok := oa.enterStruct(f)
+	if !ok {
+		return fields, required
+	}
+	defer oa.leaveStruct(name)
+	collected := make([]*structField, 0)
+	oa.collectFields(f, tagName, 0, false, &collected, nil)
+	for _, field := range dominantFields(f.TypeName, collected) {
+		fields[field.name] = field.schema
+		if field.required {
+			required = append(required, field.name)
 		}
+	}
+	return fields, required
+}
 
-		defaultValue := ""
-		defaultValue = oa.getTagByName(field.GetTag(), defaultValue, "default")
-		comment := oa.getComment(field.Comment)
-		example := ""
-		example = oa.getTagByName(field.GetTag(), example, "example")
-
-		fieldSchema := oa.NewFieldProp(field, tagName, defaultValue, comment, example)
-		fields[fieldName] = fieldSchema
-	})
+// exampleTime 时间字段固定的示例值, 保证每次生成的文档一致
+var exampleTime = time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
 
-	return fields
-}
 func getFormat(typeString string) string {
 	switch typeString {
 	case "int64", "uint64":
@@ -76,111 +75,137 @@ func getFormat(typeString string) string {
 
 func (oa *OpenAPI) NewFieldProp(f *types.Field,
 	tagName string, defaultValue string, comment string, exampleValue string) *spec.RefOrSpec[spec.Schema] {
+	schema := oa.newFieldProp(f, tagName, defaultValue, comment, exampleValue)
+	if isPointerField(f) {
+		return nullable(schema)
+	}
+	return schema
+}
+
+// isPointerField *T 类型的字段, 序列化时可能为null
+func isPointerField(f *types.Field) bool {
+	return !f.Slice && (f.Pointer || strings.HasPrefix(f.Type, "*"))
+}
+
+// isOmitEmpty
+// 标签中带omitempty的字段可能不出现在序列化结果中, 不能列入required
+// (同一个component同时用于请求和响应)
+func isOmitEmpty(tag reflect.StructTag, tagName string) bool {
+	opts := strings.Split(tag.Get(tagName), ",")
+	for _, opt := range opts[1:] {
+		if opt == "omitempty" || opt == "omitzero" {
+			return true
+		}
+	}
+	return false
+}
+
+// nullable
+// 使用OpenAPI 3.1的类型数组表示可为null: type: [string, "null"],
+// $ref 及没有type的schema使用 oneOf: [schema, {type: "null"}]
+func nullable(schema *spec.RefOrSpec[spec.Schema]) *spec.RefOrSpec[spec.Schema] {
+	if schema.Spec != nil && schema.Spec.Type != nil {
+		for _, t := range *schema.Spec.Type {
+			if t == "null" {
+				return schema
+			}
+		}
+		schema.Spec.Type.Add("null")
+		return schema
+	}
+	return spec.NewSchemaBuilder().OneOf(schema, spec.NewSchemaBuilder().Type("null").Build()).Build()
+}
+
+func (oa *OpenAPI) newFieldProp(f *types.Field,
+	tagName string, defaultValue string, comment string, exampleValue string) *spec.RefOrSpec[spec.Schema] {
 
 	typeString := f.Type
+	if t := strings.TrimLeft(strings.TrimPrefix(typeString, "[]"), "*"); strings.HasPrefix(t, "map[") {
+		schema := oa.mapSchema(f, t, tagName)
+		if schema.Spec != nil && comment != "" {
+			schema.Spec.Description = comment
+		}
+		if f.Slice || strings.HasPrefix(typeString, "[]") {
+			schema = spec.NewSchemaBuilder().Type("array").Items(spec.NewBoolOrSchema(schema)).Description(comment).Build()
+		}
+		return withExample(schema, exampleValue)
+	}
+	if schema := oa.polymorphicSchema(f); schema != nil {
+		if f.Slice {
+			schema = spec.NewSchemaBuilder().Type("array").Items(spec.NewBoolOrSchema(schema)).Description(comment).Build()
+		} else if schema.Spec != nil {
+			schema.Spec.Description = comment
+		}
+		return withExample(schema, exampleValue)
+	}
+	if schema := oa.mappedFieldSchema(f, tagName, defaultValue, comment, exampleValue); schema != nil {
+		return schema
+	}
+	if schema := oa.timeFieldSchema(f, tagName, defaultValue, comment, exampleValue); schema != nil {
+		return schema
+	}
+	if schema := oa.namedFieldSchema(f, tagName, defaultValue, comment, exampleValue); schema != nil {
+		return schema
+	}
 	var defVal, exampleVal any = defaultValue, exampleValue
 	isObject := false
 	isBinary := false
 	format := ""
 
-	if strings.Contains(typeString, "Decimal") {
+	switch typeString {
+	case "string":
+		typeString = "string"
+		defVal = defaultValue
+		exampleVal = exampleValue
+	case "int", "int64", "uint", "uint64", "uint32", "int32":
+		format = getFormat(typeString)
+		typeString = "integer"
+		if defaultValue != "" {
+			defVal = conv.Int(defaultValue)
+		}
+		if exampleValue == "" {
+			exampleVal = 0
+		} else {
+			exampleVal = conv.Int(exampleValue)
+		}
+	case "bool":
+		typeString = "boolean"
+		if defaultValue != "" {
+			defVal = conv.Bool(defaultValue)
+		}
+		if exampleValue == "" {
+			exampleVal = false
+		} else {
+			exampleVal = conv.Bool(exampleValue)
+		}
+	case "float32", "float64":
+		format = getFormat(typeString)
 		typeString = "number"
-		format = "double"
-		defVal = 0.0
-		exampleVal = 0.0
-	} else {
-		switch typeString {
-		case "string":
-			typeString = "string"
-			defVal = defaultValue
-			exampleVal = exampleValue
-		case "int", "int64", "uint", "uint64", "uint32", "int32":
-			format = getFormat(typeString)
-			typeString = "integer"
-			if defaultValue == "" {
-				defVal = 0
-			} else {
-				defVal = conv.Int(defaultValue)
-			}
-			if exampleValue == "" {
-				exampleVal = 0
-			} else {
-				exampleVal = conv.Int(exampleValue)
-			}
-		case "bool":
-			typeString = "boolean"
-			if defaultValue == "" {
-				defVal = false
-			} else {
-				defVal = conv.Bool(defaultValue)
-			}
-			if exampleValue == "" {
-				exampleVal = false
-			} else {
-				exampleVal = conv.Bool(exampleValue)
-			}
-		case "float32", "float64":
-			format = getFormat(typeString)
-			typeString = "number"
-			if defaultValue == "" {
-				defVal = 0.00
-			} else {
-				defVal = conv.Float64(defaultValue)
-			}
-			if exampleValue == "" {
-				exampleVal = 0.00
-			} else {
-				exampleVal = conv.Float64(exampleValue)
-			}
-		case "Time":
-			if v, ok := f.GetTag().Lookup("time_format"); ok {
-				switch v {
-				case "unix":
-					typeString = "integer"
-					format = "int64"
-					if defaultValue == "" {
-						defVal = time.Now().Unix()
-					}
-					if exampleValue == "" {
-						exampleVal = time.Now().Unix()
-					}
-				case "unixnano":
-					typeString = "integer"
-					format = "int64"
-					if defaultValue == "" {
-						defVal = time.Now().UnixNano()
-					}
-					if exampleValue == "" {
-						exampleVal = time.Now().UnixNano()
-					}
-				}
-			} else {
-				typeString = "string"
-				format = "date-time"
-				if defaultValue == "" {
-					defVal = time.Now().Format("2006-01-02 15:04:05")
-				}
-				if exampleValue == "" {
-					exampleVal = time.Now().Format("2006-01-02 15:04:05")
-				}
-			}
-
-		case "FileHeader":
-			typeString = "string"
-			isBinary = true
-			format = "binary"
-			isObject = true
-			defVal = ""
-			exampleVal = ""
-		default:
-			isObject = true
+		if defaultValue != "" {
+			defVal = conv.Float64(defaultValue)
+		}
+		if exampleValue == "" {
+			exampleVal = 0.00
+		} else {
+			exampleVal = conv.Float64(exampleValue)
 		}
+	case "FileHeader":
+		typeString = "string"
+		isBinary = true
+		format = "binary"
+		isObject = true
+		defVal = ""
+		exampleVal = ""
+	default:
+		isObject = true
 	}
 	builder := spec.NewSchemaBuilder()
 	if f.Slice {
+		// example为JSON数组时作为整个数组的示例, 否则作为元素的示例
+		arrayExample := strings.HasPrefix(strings.TrimSpace(exampleValue), "[")
 		var schema *spec.RefOrSpec[spec.Schema]
 		if isObject {
-			schema = oa.NewObjectProp(f.Struct, tagName)
+			schema = oa.structSchema(f.Struct, tagName)
 		} else {
 			builder1 := spec.NewSchemaBuilder()
 			builder1.Type(typeString)
@@ -190,13 +215,16 @@ func (oa *OpenAPI) NewFieldProp(f *types.Field,
 			if defVal != "" {
 				builder1.Default(defVal)
 			}
-			if exampleVal != "" {
+			if exampleVal != "" && !arrayExample {
 				builder1.Example(exampleVal)
 			}
 			schema = builder1.Build()
 
 		}
 		builder.Type("array").Items(spec.NewBoolOrSchema(schema))
+		if arrayExample {
+			return withExample(builder.Build(), exampleValue)
+		}
 
 	} else {
 		if isObject {
@@ -204,7 +232,7 @@ func (oa *OpenAPI) NewFieldProp(f *types.Field,
 			if isBinary {
 				schema = spec.NewSchemaBuilder().Type("string").Format(format).Build()
 			} else {
-				schema = oa.NewObjectProp(f.Struct, tagName)
+				schema = withExample(oa.structSchema(f.Struct, tagName), exampleValue)
 			}
 
 			return schema
@@ -222,67 +250,195 @@ func (oa *OpenAPI) NewFieldProp(f *types.Field,
 	return builder.Build()
 }
 
-func (oa *OpenAPI) NewObjectProp(f *types.Struct, tagName string) *spec.RefOrSpec[spec.Schema] {
-	if f.IsEnum() {
-		doc := oa.getComment(f.Doc)
-		builder := spec.NewSchemaBuilder().Type(f.Type).Comment(doc)
-		description := ""
-		var enumDesc = make([]*types.Comment, 0)
-		for _, enum := range f.Enum.Enums {
-			builder.AddEnum(enum.Value)
-			comment := oa.getComment(enum.Comment)
-			if comment != "" {
-				comment = fmt.Sprintf("(%s)", comment)
-			}
-			description += fmt.Sprintf("- %s: %s%s\n", conv.String(enum.Value), enum.Name, comment)
-			enumDesc = append(enumDesc, &types.Comment{
-				Content: fmt.Sprintf("- %s: %s%s\n", conv.String(enum.Value), enum.Name, comment),
-			})
+// mapSchema
+// map字段生成 type: object, additionalProperties为值类型的schema, 非string的key生成propertyNames约束
+//
+//	map[string]Price => {type: object, additionalProperties: {$ref: Price}}
+//	map[int]string   => {type: object, additionalProperties: {type: string}, propertyNames: {pattern: ^-?[0-9]+$}}
+func (oa *OpenAPI) mapSchema(f *types.Field, typ string, tagName string) *spec.RefOrSpec[spec.Schema] {
+	key, value, ok := splitMapType(typ)
+	builder := spec.NewSchemaBuilder().Type("object")
+	if !ok {
+		return builder.Build()
+	}
+	builder.AdditionalProperties(spec.NewBoolOrSchema(oa.mapValueSchema(f, value, tagName)))
+	if names := oa.mapKeySchema(key); names != nil {
+		builder.PropertyNames(names)
+	}
+	return builder.Build()
+}
+
+func (oa *OpenAPI) mapValueSchema(f *types.Field, value string, tagName string) *spec.RefOrSpec[spec.Schema] {
+	value = strings.TrimLeft(value, "*")
+	switch {
+	case strings.HasPrefix(value, "[]"):
+		items := oa.mapValueSchema(f, value[2:], tagName)
+		return spec.NewSchemaBuilder().Type("array").Items(spec.NewBoolOrSchema(items)).Build()
+	case strings.HasPrefix(value, "map["):
+		return oa.mapSchema(f, value, tagName)
+	}
+	if b := primitiveSchema(value); b != nil {
+		return b.Build()
+	}
+	if schema := oa.typeMapping(value, nil); schema != nil {
+		return schema
+	}
+	// 值为结构体时字段上解析出的Struct即为值类型
+	if f.Struct != nil {
+		return oa.structSchema(f.Struct, tagName)
+	}
+	if s, ok := oa.lookupStruct(value); ok {
+		return oa.structSchema(s, tagName)
+	}
+	return spec.NewSchemaBuilder().Build()
+}
+
+// mapKeySchema map的key只能序列化为字符串, 整数(包括具名的整数类型)和枚举key通过propertyNames约束
+func (oa *OpenAPI) mapKeySchema(key string) *spec.RefOrSpec[spec.Schema] {
+	switch key {
+	case "string":
+		return nil
+	case "int", "int8", "int16", "int32", "int64":
+		return spec.NewSchemaBuilder().Pattern("^-?[0-9]+$").Build()
+	case "uint", "uint8", "uint16", "uint32", "uint64":
+		return spec.NewSchemaBuilder().Pattern("^[0-9]+$").Build()
+	}
+	s, ok := oa.lookupStruct(key)
+	if !ok {
+		return nil
+	}
+	if s.IsEnum() {
+		builder := spec.NewSchemaBuilder()
+		for _, enum := range s.Enum.Enums {
+			builder.AddEnum(enumValue("string", enum.Value))
 		}
-		f.Enum.Comment = enumDesc
-		builder.Description(description)
 		return builder.Build()
-	} else {
-		builder := spec.NewSchemaBuilder().Type("object")
-		f.VisitFields(func(element *types.Field) bool {
-			return !element.Private
-		}, func(field *types.Field) {
-			fieldName := field.Name
-			fieldName = oa.getTagByName(field.GetTag(), fieldName, tagName)
-			// 处理隐式导入的字段
-			if fieldName == "-" || field.Name == constants.EmptyName {
-				if field.Name == constants.EmptyName {
-					fields := oa.NewParentFieldProp(field.Struct, tagName)
-					for key, v := range fields {
-						builder.AddProperty(key, v)
-					}
-					return
-				}
-				return
+	}
+	// 具名的整数类型(type UserID int64)按底层类型约束
+	if underlying := underlyingType(s); underlying != "" && underlying != key && primitiveSchema(underlying) != nil {
+		return oa.mapKeySchema(underlying)
+	}
+	return nil
+}
+
+// splitMapType 拆分 map[K]V 为 K 和 V
+func splitMapType(t string) (string, string, bool) {
+	if !strings.HasPrefix(t, "map[") {
+		return "", "", false
+	}
+	depth := 0
+	for i := 3; i < len(t); i++ {
+		switch t[i] {
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				return t[4:i], t[i+1:], true
 			}
+		}
+	}
+	return "", "", false
+}
+
+// structSchema
+// 字段引用的结构体注册到当前分组的components/schemas中并返回$ref,
+// 匿名结构体、非json标签(form/query等)及配置了inlineStructs时直接内联
+func (oa *OpenAPI) structSchema(s *types.Struct, tagName string) *spec.RefOrSpec[spec.Schema] {
+	if s == nil {
+		return spec.NewSchemaBuilder().Type("object").Build()
+	}
+	if tagName != "json" || isAnonymousStruct(s) || oa.openApiMiddleware.Options().InlineStructs {
+		return oa.NewObjectProp(s, tagName)
+	}
+	name := oa.schemaName(s)
+	if oa.visiting[name] {
+		return oa.backRef(s)
+	}
+	g := oa.getCurrentGroup(oa.currentGroup)
+	if !hasSchema(g, name) {
+		g.AddComponent(name, oa.NewObjectProp(s, tagName))
+	}
+	return spec.NewSchemaBuilder().Ref("#/components/schemas/" + name).Build()
+}
 
-			defaultValue := ""
-			defaultValue = oa.getTagByName(field.GetTag(), defaultValue, "default")
-			comment := oa.getComment(field.Comment)
-			example := ""
-			example = oa.getTagByName(field.GetTag(), example, "example")
-			//
-			if field.Parent && field.Struct != nil {
-				if field.Generic {
-					schema := oa.NewFieldProp(field, tagName, defaultValue, comment, example)
-					builder.AddProperty(fieldName, schema)
-				} else {
-					fields := oa.NewParentFieldProp(field.Struct, tagName)
-					builder.Properties(fields)
-				}
+// enterStruct
+// 标记结构体正在生成schema, 已在生成中(递归类型)时返回false
+func (oa *OpenAPI) enterStruct(s *types.Struct) (string, bool) {
+	if isAnonymousStruct(s) {
+		return "", true
+	}
+	name := oa.schemaName(s)
+	if oa.visiting[name] {
+		return name, false
+	}
+	if oa.visiting == nil {
+		oa.visiting = make(map[string]bool)
+	}
+	oa.visiting[name] = true
+	return name, true
+}
 
-			} else {
-				schema := oa.NewFieldProp(field, tagName, defaultValue, comment, example)
+func (oa *OpenAPI) leaveStruct(name string) {
+	delete(oa.visiting, name)
+}
 
-				builder.AddProperty(fieldName, schema)
-			}
+// backRef 递归类型引用自身时使用$ref, 并保证该结构体注册到components中
+func (oa *OpenAPI) backRef(s *types.Struct) *spec.RefOrSpec[spec.Schema] {
+	return spec.NewSchemaBuilder().Ref("#/components/schemas/" + oa.requireStruct(oa.currentGroup, s)).Build()
+}
+
+func isAnonymousStruct(s *types.Struct) bool {
+	return s.TypeName == "" || strings.HasPrefix(s.TypeName, "struct")
+}
 
-		})
+// hasSchema 分组的components/schemas中是否已有name
+func hasSchema(g *spec.OpenAPIBuilder, name string) bool {
+	components := g.Build().Spec.Components
+	if components == nil {
+		return false
+	}
+	_, ok := components.Spec.Schemas[name]
+	return ok
+}
+
+func (oa *OpenAPI) NewObjectProp(f *types.Struct, tagName string) *spec.RefOrSpec[spec.Schema] {
+	oa.rememberStruct(f)
+	if schema := oa.customSchema(f); schema != nil {
+		return schema
+	}
+	if schema := oa.namedTypeSchema(f); schema != nil {
+		return schema
+	}
+	if f.IsEnum() {
+		return oa.enumSchema(f)
+	} else {
+		name, ok := oa.enterStruct(f)
+		if !ok {
+			return oa.backRef(f)
+		}
+		defer oa.leaveStruct(name)
+		builder := spec.NewSchemaBuilder().Type("object")
+		if root := xmlRootName(f); root != "" {
+			builder.XML(spec.NewXMLBuilder().Name(root).Build())
+		}
+		// 配置了embedAllOf时, 嵌入的结构体使用 allOf + $ref 表示, 否则将其字段展开
+		var embeds []*spec.RefOrSpec[spec.Schema]
+		var embedsPtr *[]*spec.RefOrSpec[spec.Schema]
+		if oa.openApiMiddleware.Options().EmbedAllOf {
+			embedsPtr = &embeds
+		}
+		fields := make([]*structField, 0)
+		oa.collectFields(f, tagName, 0, false, &fields, embedsPtr)
+		for _, field := range dominantFields(f.TypeName, fields) {
+			builder.AddProperty(field.name, field.schema)
+			if field.required {
+				builder.AddRequired(field.name)
+			}
+		}
+		if len(embeds) > 0 {
+			return spec.NewSchemaBuilder().AllOf(append(embeds, builder.Build())...).Build()
+		}
 		return builder.Build()
 	}
 
@@ -308,6 +464,8 @@ func (oa *OpenAPI) NewObjectParameters(f *types.Struct, tagName string) []*spec.
 		example := ""
 		example = oa.getTagByName(field.GetTag(), example, "example")
 		schema := oa.NewFieldProp(field, tagName, defaultValue, comment, example)
+		rules := parseValidateRules(field.GetTag())
+		applyValidateRules(schema, rules)
 		if field.Struct.IsEnum() {
 			comment += "\n"
 			for _, c := range field.Struct.Enum.Comment {
@@ -319,8 +477,37 @@ func (oa *OpenAPI) NewObjectParameters(f *types.Struct, tagName string) []*spec.
 		builder.Name(fieldName)
 		builder.Description(comment)
 		builder.In(tagName)
+		// 路径参数总是必填
+		builder.Required(tagName == "path" || hasRequiredRule(rules))
 		builder.Schema(schema)
 		parameters = append(parameters, builder.Build())
 	})
 	return parameters
 }
+
+// mergeParameters
+// 合并operation中来自path/query/header/cookie等多个来源的参数, 按(name, in)去重,
+// 同名参数定义不一致时输出警告并保留先出现的定义
+func mergeParameters(operationID string, params []*spec.RefOrSpec[spec.Extendable[spec.Parameter]]) []*spec.RefOrSpec[spec.Extendable[spec.Parameter]] {
+	result := make([]*spec.RefOrSpec[spec.Extendable[spec.Parameter]], 0, len(params))
+	seen := make(map[string]*spec.RefOrSpec[spec.Extendable[spec.Parameter]], len(params))
+	for _, p := range params {
+		if p == nil || p.Spec == nil {
+			result = append(result, p)
+			continue
+		}
+		key := p.Spec.Spec.In + ":" + p.Spec.Spec.Name
+		if first, ok := seen[key]; ok {
+			a, _ := json.Marshal(first)
+			b, _ := json.Marshal(p)
+			if !bytes.Equal(a, b) {
+				pterm.Warning.Printfln("openapi: %s declares %s parameter %s more than once with different definitions, the first one is used",
+					operationID, p.Spec.Spec.In, p.Spec.Spec.Name)
+			}
+			continue
+		}
+		seen[key] = p
+		result = append(result, p)
+	}
+	return result
+}
@@ -0,0 +1,65 @@
+package fw_openapi
+
+import (
+	"reflect"
+	"testing"
+
+	spec "github.com/sv-tools/openapi"
+)
+
+func TestMergeParameters(t *testing.T) {
+	param := func(in string, name string, typ string) *spec.RefOrSpec[spec.Extendable[spec.Parameter]] {
+		return spec.NewParameterBuilder().In(in).Name(name).Schema(spec.NewSchemaBuilder().Type(typ).Build()).Build()
+	}
+	tests := []struct {
+		name   string
+		params []*spec.RefOrSpec[spec.Extendable[spec.Parameter]]
+		want   []string
+	}{
+		{
+			name: "distinct parameters are kept in order",
+			params: []*spec.RefOrSpec[spec.Extendable[spec.Parameter]]{
+				param("path", "id", "integer"),
+				param("query", "page", "integer"),
+				param("header", "X-Token", "string"),
+			},
+			want: []string{"path:id:integer", "query:page:integer", "header:X-Token:string"},
+		},
+		{
+			name: "same name in different locations is kept",
+			params: []*spec.RefOrSpec[spec.Extendable[spec.Parameter]]{
+				param("query", "id", "string"),
+				param("path", "id", "integer"),
+			},
+			want: []string{"query:id:string", "path:id:integer"},
+		},
+		{
+			name: "identical duplicates are merged",
+			params: []*spec.RefOrSpec[spec.Extendable[spec.Parameter]]{
+				param("query", "page", "integer"),
+				param("query", "page", "integer"),
+			},
+			want: []string{"query:page:integer"},
+		},
+		{
+			name: "the first of conflicting duplicates wins",
+			params: []*spec.RefOrSpec[spec.Extendable[spec.Parameter]]{
+				param("query", "page", "integer"),
+				param("query", "size", "integer"),
+				param("query", "page", "string"),
+			},
+			want: []string{"query:page:integer", "query:size:integer"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make([]string, 0)
+			for _, p := range mergeParameters("op", tt.params) {
+				got = append(got, p.Spec.Spec.In+":"+p.Spec.Spec.Name+":"+schemaType(p.Spec.Spec.Schema.Spec))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergeParameters() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
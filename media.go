@@ -0,0 +1,175 @@
+package fw_openapi
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/linxlib/astp/types"
+	spec "github.com/sv-tools/openapi"
+)
+
+// mediaTypeAliases @Accept/@Produce 中可以使用的简写
+var mediaTypeAliases = map[string]string{
+	"json":      "application/json",
+	"xml":       "application/xml",
+	"yaml":      "application/yaml",
+	"form":      "application/x-www-form-urlencoded",
+	"multipart": "multipart/form-data",
+	"plain":     "text/plain",
+	"text":      "text/plain",
+	"html":      "text/html",
+	"csv":       "text/csv",
+	"pdf":       "application/pdf",
+	"octet":     "application/octet-stream",
+	"stream":    "application/octet-stream",
+	"binary":    "application/octet-stream",
+	"png":       "image/png",
+	"jpeg":      "image/jpeg",
+	"zip":       "application/zip",
+	"xlsx":      "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+// parseMediaTypes 解析 @Accept/@Produce 的值, 多个类型用逗号或空格分隔
+//
+//	@Accept json,xml
+//	@Produce json xml text/csv
+func parseMediaTypes(value string) []string {
+	result := make([]string, 0)
+	for _, v := range strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '|'
+	}) {
+		if alias, ok := mediaTypeAliases[strings.ToLower(v)]; ok {
+			v = alias
+		}
+		result = append(result, v)
+	}
+	return result
+}
+
+// isStructuredMediaType json/xml/yaml等序列化结构体的媒体类型, 使用结构体的schema
+func isStructuredMediaType(mediaType string) bool {
+	mediaType, _, _ = strings.Cut(mediaType, ";")
+	return strings.HasSuffix(mediaType, "/json") || strings.HasSuffix(mediaType, "+json") ||
+		strings.HasSuffix(mediaType, "/xml") || strings.HasSuffix(mediaType, "+xml") ||
+		strings.HasSuffix(mediaType, "/yaml") || strings.HasSuffix(mediaType, "/x-yaml")
+}
+
+// negotiateContent
+// 按声明的媒体类型重新生成content, 结构化的类型共用原有的schema(没有时为任意值),
+// 表单类型按form/multipart标签生成, 文本类型为string, 其他(pdf等)为二进制
+func (oa *OpenAPI) negotiateContent(content map[string]*spec.Extendable[spec.MediaType], mediaTypes []string, s *types.Struct) map[string]*spec.Extendable[spec.MediaType] {
+	var source *spec.RefOrSpec[spec.Schema]
+	if mt, ok := content["application/json"]; ok {
+		source = mt.Spec.Schema
+	} else {
+		keys := make([]string, 0, len(content))
+		for k := range content {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		if len(keys) > 0 {
+			source = content[keys[0]].Spec.Schema
+		}
+	}
+	result := make(map[string]*spec.Extendable[spec.MediaType], len(mediaTypes))
+	for _, mediaType := range mediaTypes {
+		var schema *spec.RefOrSpec[spec.Schema]
+		switch {
+		case isStructuredMediaType(mediaType):
+			schema = source
+			if schema == nil {
+				schema = spec.NewSchemaBuilder().Build()
+			}
+		case mediaType == "application/x-www-form-urlencoded" && s != nil:
+			schema = oa.NewObjectProp(s, "form")
+		case mediaType == "multipart/form-data" && s != nil:
+			schema = oa.NewObjectProp(s, "multipart")
+		case strings.HasPrefix(mediaType, "text/"):
+			schema = spec.NewSchemaBuilder().Type("string").Build()
+		default:
+			schema = spec.NewSchemaBuilder().Type("string").Format("binary").Build()
+		}
+		result[mediaType] = spec.NewMediaTypeBuilder().Schema(schema).Build()
+	}
+	return result
+}
+
+// xmlRootName 结构体中 XMLName xml.Name `xml:"user"` 指定的根元素名称
+func xmlRootName(s *types.Struct) string {
+	name := ""
+	s.VisitFields(func(element *types.Field) bool {
+		return element.Name == "XMLName"
+	}, func(field *types.Field) {
+		name = xmlElementName(field.GetTag().Get("xml"))
+	})
+	return name
+}
+
+// xmlElementName xml标签中的元素名称, xml:"namespace name,attr" 取name
+func xmlElementName(tag string) string {
+	name, _, _ := strings.Cut(tag, ",")
+	if i := strings.LastIndex(name, " "); i >= 0 {
+		name = name[i+1:]
+	}
+	return name
+}
+
+// applyXMLTag
+// 按xml标签为属性生成xml对象, 与encoding/xml一致:
+//
+//	xml:"id,attr"         => xml: {name: id, attribute: true}
+//	xml:"items>item"      => 数组 xml: {name: items, wrapped: true}, 元素 xml: {name: item}
+//	xml:"tag" (数组)      => 元素 xml: {name: tag}
+//
+// 与json名称相同且没有特殊选项时不生成
+func applyXMLTag(schema *spec.RefOrSpec[spec.Schema], tag reflect.StructTag, name string) *spec.RefOrSpec[spec.Schema] {
+	v, ok := tag.Lookup("xml")
+	if !ok || v == "-" || schema == nil {
+		return schema
+	}
+	xmlName, opts, _ := strings.Cut(v, ",")
+	attr := false
+	for _, opt := range strings.Split(opts, ",") {
+		switch opt {
+		case "attr":
+			attr = true
+		case "chardata", "innerxml", "comment":
+			return schema
+		}
+	}
+	if i := strings.LastIndex(xmlName, " "); i >= 0 {
+		// xml:"namespace name"
+		xmlName = xmlName[i+1:]
+	}
+	parents := strings.Split(xmlName, ">")
+	xmlName = parents[len(parents)-1]
+	if xmlName == "" {
+		xmlName = name
+	}
+	isArray := schema.Spec != nil && schemaType(schema.Spec) == "array" && schema.Spec.Items != nil && schema.Spec.Items.Schema != nil
+	if isArray {
+		if xmlName == name && len(parents) == 1 {
+			return schema
+		}
+		items := xmlSchema(schema.Spec.Items.Schema, spec.NewXMLBuilder().Name(xmlName).Build())
+		schema.Spec.Items = spec.NewBoolOrSchema(items)
+		if len(parents) > 1 {
+			schema.Spec.XML = spec.NewXMLBuilder().Name(parents[len(parents)-2]).Wrapped(true).Build()
+		}
+		return schema
+	}
+	if xmlName == name && !attr {
+		return schema
+	}
+	return xmlSchema(schema, spec.NewXMLBuilder().Name(xmlName).Attribute(attr).Build())
+}
+
+// xmlSchema 设置schema的xml对象, $ref 使用 allOf: [$ref] 包装
+func xmlSchema(schema *spec.RefOrSpec[spec.Schema], xml *spec.Extendable[spec.XML]) *spec.RefOrSpec[spec.Schema] {
+	if schema.Spec != nil {
+		schema.Spec.XML = xml
+		return schema
+	}
+	return spec.NewSchemaBuilder().AllOf(schema).XML(xml).Build()
+}
@@ -0,0 +1,121 @@
+package fw_openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	spec "github.com/sv-tools/openapi"
+)
+
+func TestParseMediaTypes(t *testing.T) {
+	tests := []struct {
+		value string
+		want  []string
+	}{
+		{value: "json", want: []string{"application/json"}},
+		{value: "json,xml", want: []string{"application/json", "application/xml"}},
+		{value: "JSON xml text/csv", want: []string{"application/json", "application/xml", "text/csv"}},
+		{value: "form|multipart", want: []string{"application/x-www-form-urlencoded", "multipart/form-data"}},
+		{value: "application/vnd.api+json", want: []string{"application/vnd.api+json"}},
+		{value: " , ", want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if got := parseMediaTypes(tt.value); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseMediaTypes(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyXMLTag(t *testing.T) {
+	str := func() *spec.RefOrSpec[spec.Schema] {
+		return spec.NewSchemaBuilder().Type("string").Build()
+	}
+	arr := func() *spec.RefOrSpec[spec.Schema] {
+		return spec.NewSchemaBuilder().Type("array").Items(spec.NewBoolOrSchema(str())).Build()
+	}
+	tests := []struct {
+		name   string
+		schema *spec.RefOrSpec[spec.Schema]
+		tag    string
+		field  string
+		want   string
+	}{
+		{
+			name:   "no xml tag",
+			schema: str(),
+			tag:    `json:"id"`,
+			field:  "id",
+			want:   `{"type":"string"}`,
+		},
+		{
+			name:   "same name",
+			schema: str(),
+			tag:    `json:"id" xml:"id"`,
+			field:  "id",
+			want:   `{"type":"string"}`,
+		},
+		{
+			name:   "attribute",
+			schema: str(),
+			tag:    `xml:"id,attr"`,
+			field:  "id",
+			want:   `{"type":"string","xml":{"attribute":true,"name":"id"}}`,
+		},
+		{
+			name:   "renamed element",
+			schema: str(),
+			tag:    `json:"id" xml:"ID"`,
+			field:  "id",
+			want:   `{"type":"string","xml":{"name":"ID"}}`,
+		},
+		{
+			name:   "namespaced element",
+			schema: str(),
+			tag:    `xml:"http://example.com/ns title"`,
+			field:  "title",
+			want:   `{"type":"string"}`,
+		},
+		{
+			name:   "chardata is left alone",
+			schema: str(),
+			tag:    `xml:",chardata"`,
+			field:  "Value",
+			want:   `{"type":"string"}`,
+		},
+		{
+			name:   "wrapped array",
+			schema: arr(),
+			tag:    `xml:"items>item"`,
+			field:  "items",
+			want:   `{"items":{"type":"string","xml":{"name":"item"}},"type":"array","xml":{"name":"items","wrapped":true}}`,
+		},
+		{
+			name:   "renamed array elements",
+			schema: arr(),
+			tag:    `json:"tags" xml:"tag"`,
+			field:  "tags",
+			want:   `{"items":{"type":"string","xml":{"name":"tag"}},"type":"array"}`,
+		},
+		{
+			name:   "reference gets an allOf wrapper",
+			schema: spec.NewSchemaBuilder().Ref("#/components/schemas/User").Build(),
+			tag:    `xml:"owner"`,
+			field:  "user",
+			want:   `{"allOf":[{"$ref":"#/components/schemas/User"}],"xml":{"name":"owner"}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := json.Marshal(applyXMLTag(tt.schema, reflect.StructTag(tt.tag), tt.field))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("applyXMLTag(%s) = %s, want %s", tt.tag, got, tt.want)
+			}
+		})
+	}
+}
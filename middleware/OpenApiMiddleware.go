@@ -27,6 +27,28 @@ type OpenApiOptions struct {
 	Path           string `yaml:"path" default:"/docs"`
 	GroupQueryName string `yaml:"groupQueryName" default:"urls.primaryName"`
 	OpenApiPath    string `yaml:"openApiPath" default:"/openapi.json"`
+	// ErrorModel Go struct name used as the error envelope, resolved with astp
+	ErrorModel string `yaml:"errorModel"`
+	// ErrorFields error envelope fields when there is no Go struct. field name -> type (go or json type)
+	ErrorFields map[string]string `yaml:"errorFields"`
+	// Wrapper Go generic struct name wrapping every success response, e.g. Result
+	Wrapper string `yaml:"wrapper"`
+	// WrapperDataField field of the wrapper holding the handler result
+	WrapperDataField string `yaml:"wrapperDataField" default:"data"`
+	// WrapperFields wrapper fields when there is no Go struct. field name -> type (go or json type)
+	WrapperFields map[string]string `yaml:"wrapperFields"`
+	// InlineStructs inline nested structs instead of referencing them from components/schemas
+	InlineStructs bool `yaml:"inlineStructs" default:"false"`
+	// EmbedAllOf express embedded structs as allOf with a $ref to the embedded struct instead of flattening their fields
+	EmbedAllOf bool `yaml:"embedAllOf" default:"false"`
+	// NamedTypes register named primitive types (type UserID int64) in components/schemas instead of inlining them
+	NamedTypes bool `yaml:"namedTypes" default:"false"`
+	// SortOutput sort tags, parameters and required fields so the generated document is byte-for-byte reproducible
+	SortOutput bool `yaml:"sortOutput" default:"false"`
+	// DisableExampleSynthesis do not generate examples for request bodies and responses without one
+	DisableExampleSynthesis bool `yaml:"disableExampleSynthesis" default:"false"`
+	// Naming how structs are named in components/schemas. short: PageOfUser, package: model.PageOfUser
+	Naming string `yaml:"naming" default:"short"`
 }
 
 type OpenApiMiddleware struct {
@@ -144,6 +166,10 @@ func (o *OpenApiMiddleware) Router(ctx *fw.MiddlewareContext) []*fw.RouteItem {
 	return ris
 }
 
+func (o *OpenApiMiddleware) Options() *OpenApiOptions {
+	return o.options
+}
+
 func (o *OpenApiMiddleware) GetDocType() string {
 	return o.options.Type
 }
@@ -0,0 +1,270 @@
+package fw_openapi
+
+import (
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/linxlib/astp/types"
+	"github.com/pterm/pterm"
+	spec "github.com/sv-tools/openapi"
+)
+
+// RegisterModels
+// 注册只在注解(@Response/@Failure/@OneOf/@AnyOf/@ErrorModel/@Wrapper)中按名称引用的类型.
+// 注解中的类型名先在解析方法时遇到的结构体中查找, 类型从未作为方法的参数或返回值出现时
+// 只能通过注册找到, 否则输出警告(@OneOf/@AnyOf 中去掉该类型, 其他注解使用默认结构).
+//
+// 注册的类型按反射生成schema, 字段规则(标签、校验、time_format、xml、嵌入)与解析源码时相同,
+// 源码中解析过的类型(包括嵌套的字段类型)优先使用解析的结果. 反射无法得到的信息不会生成:
+// 类型及字段的注释、枚举的取值(x-enum-varnames)、字段上的 @OneOf/@Discriminator 注解.
+// 实现了 OpenAPISchemaProvider 的类型同时按 RegisterSchemaProvider 注册
+//
+//	oa.RegisterModels(CardPayment{}, BankPayment{}, ApiError{}, Result[any]{})
+func (oa *OpenAPI) RegisterModels(models ...any) {
+	for _, m := range models {
+		t := reflect.TypeOf(m)
+		for t != nil && t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		if t == nil || t.Name() == "" {
+			pterm.Warning.Printfln("openapi: model %T is not a named type, ignored", m)
+			continue
+		}
+		if oa.models == nil {
+			oa.models = make(map[string]reflect.Type)
+			oa.modelIndex = make(map[string][]string)
+		}
+		identity := t.PkgPath() + "." + t.Name()
+		if _, ok := oa.models[identity]; ok {
+			continue
+		}
+		oa.models[identity] = t
+		if p, ok := schemaProvider(t); ok {
+			oa.RegisterTypeMapping(identity, p.OpenAPISchema())
+		}
+		base, _, _ := strings.Cut(t.Name(), "[")
+		for _, short := range []string{componentName(t.Name()), componentName(base)} {
+			ids := oa.modelIndex[short]
+			if len(ids) > 0 && ids[len(ids)-1] == identity {
+				continue
+			}
+			oa.modelIndex[short] = append(ids, identity)
+		}
+	}
+}
+
+// lookupModel 按类型名查找 RegisterModels 注册的类型, 规则与 lookupStruct 相同
+func (oa *OpenAPI) lookupModel(typeName string) (reflect.Type, bool) {
+	t := strings.TrimLeft(strings.TrimSpace(typeName), "*")
+	if m, ok := oa.models[t]; ok {
+		return m, true
+	}
+	id, ok := oa.pickIdentity(t, oa.modelIndex, func(id string) string {
+		return oa.models[id].PkgPath()
+	})
+	if !ok {
+		return nil, false
+	}
+	return oa.models[id], true
+}
+
+// modelStruct 注册类型在命名时使用的结构体, 与解析得到的同一类型共用components中的名称
+func modelStruct(t reflect.Type) *types.Struct {
+	base, _, _ := strings.Cut(t.Name(), "[")
+	return &types.Struct{Name: base, TypeName: t.Name(), PackagePath: t.PkgPath()}
+}
+
+// modelComponent 将注册的具名结构体登记到当前分组的components中, 返回其名称
+func (oa *OpenAPI) modelComponent(t reflect.Type) string {
+	name := oa.schemaName(modelStruct(t))
+	// 递归类型在 resolvePendingSchemas 中登记
+	if oa.visiting[name] {
+		return oa.requireModel(oa.currentGroup, t)
+	}
+	g := oa.getCurrentGroup(oa.currentGroup)
+	if !hasSchema(g, name) {
+		g.AddComponent(name, oa.modelComponentSchema(t))
+	}
+	return name
+}
+
+// modelComponentSchema 注册类型在components中的schema, 类型映射(包括 OpenAPISchemaProvider)优先
+func (oa *OpenAPI) modelComponentSchema(t reflect.Type) *spec.RefOrSpec[spec.Schema] {
+	if schema := oa.typeMapping(t.PkgPath()+"."+t.Name(), nil); schema != nil {
+		return schema
+	}
+	if t.Kind() == reflect.Struct {
+		return oa.modelObjectSchema(t)
+	}
+	return oa.modelSchema(t, "")
+}
+
+// requireModel 登记需要注册到分组components中的注册类型(递归类型的回引), 返回其名称
+func (oa *OpenAPI) requireModel(groupName string, t reflect.Type) string {
+	name := oa.schemaName(modelStruct(t))
+	if oa.pendingModels == nil {
+		oa.pendingModels = make(map[string]map[string]reflect.Type)
+	}
+	if oa.pendingModels[groupName] == nil {
+		oa.pendingModels[groupName] = make(map[string]reflect.Type)
+	}
+	oa.pendingModels[groupName][name] = t
+	return name
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// modelSchema
+// 按反射类型生成字段的schema, 规则与 newFieldProp 相同:
+// 类型映射优先, 源码中解析过的类型使用解析的结果, time.Time 按 time_format 标签,
+// 具名结构体引用components(配置了inlineStructs时内联), 其他类型内联
+func (oa *OpenAPI) modelSchema(t reflect.Type, tag reflect.StructTag) *spec.RefOrSpec[spec.Schema] {
+	if t.Kind() == reflect.Pointer {
+		return nullable(oa.modelSchema(t.Elem(), tag))
+	}
+	if t.Name() != "" && t.PkgPath() != "" {
+		identity := t.PkgPath() + "." + t.Name()
+		if schema := oa.typeMapping(identity, nil); schema != nil {
+			return schema
+		}
+		if s, ok := oa.structs[identity]; ok {
+			if schema := oa.namedTypeSchema(s); schema != nil && !oa.openApiMiddleware.Options().NamedTypes {
+				return schema
+			}
+			return oa.structSchema(s, "json")
+		}
+		if t == timeType {
+			return timeLayoutSchema(tag.Get("time_format")).Build()
+		}
+		if t.Kind() == reflect.Struct {
+			if oa.openApiMiddleware.Options().InlineStructs {
+				return oa.inlineModel(t)
+			}
+			return spec.NewSchemaBuilder().Ref("#/components/schemas/" + oa.modelComponent(t)).Build()
+		}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return spec.NewSchemaBuilder().Type("boolean").Build()
+	case reflect.String:
+		return spec.NewSchemaBuilder().Type("string").Build()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return primitiveSchema(t.Kind().String()).Build()
+	case reflect.Slice, reflect.Array:
+		// []byte 被encoding/json编码为base64字符串
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			return oa.typeMapping("[]byte", nil)
+		}
+		return spec.NewSchemaBuilder().Type("array").Items(spec.NewBoolOrSchema(oa.modelSchema(t.Elem(), tag))).Build()
+	case reflect.Map:
+		return spec.NewSchemaBuilder().Type("object").AdditionalProperties(spec.NewBoolOrSchema(oa.modelSchema(t.Elem(), tag))).Build()
+	case reflect.Struct:
+		return oa.modelObjectSchema(t)
+	default:
+		return spec.NewSchemaBuilder().Build()
+	}
+}
+
+// inlineModel 配置了inlineStructs时内联注册类型, 递归引用自身时使用$ref
+func (oa *OpenAPI) inlineModel(t reflect.Type) *spec.RefOrSpec[spec.Schema] {
+	name := oa.schemaName(modelStruct(t))
+	if oa.visiting[name] {
+		return spec.NewSchemaBuilder().Ref("#/components/schemas/" + oa.requireModel(oa.currentGroup, t)).Build()
+	}
+	return oa.modelObjectSchema(t)
+}
+
+// modelObjectSchema 结构体的object schema, 字段规则与 NewObjectProp 相同
+func (oa *OpenAPI) modelObjectSchema(t reflect.Type) *spec.RefOrSpec[spec.Schema] {
+	if t.Name() != "" {
+		name := oa.schemaName(modelStruct(t))
+		if oa.visiting == nil {
+			oa.visiting = make(map[string]bool)
+		}
+		oa.visiting[name] = true
+		defer delete(oa.visiting, name)
+	}
+	builder := spec.NewSchemaBuilder().Type("object")
+	if field, ok := t.FieldByName("XMLName"); ok {
+		if root := xmlElementName(field.Tag.Get("xml")); root != "" {
+			builder.XML(spec.NewXMLBuilder().Name(root).Build())
+		}
+	}
+	var embeds []*spec.RefOrSpec[spec.Schema]
+	var embedsPtr *[]*spec.RefOrSpec[spec.Schema]
+	if oa.openApiMiddleware.Options().EmbedAllOf {
+		embedsPtr = &embeds
+	}
+	fields := make([]*structField, 0)
+	oa.collectModelFields(t, 0, false, &fields, embedsPtr, map[reflect.Type]bool{t: true})
+	for _, field := range dominantFields(t.Name(), fields) {
+		builder.AddProperty(field.name, field.schema)
+		if field.required {
+			builder.AddRequired(field.name)
+		}
+	}
+	if len(embeds) > 0 {
+		return spec.NewSchemaBuilder().AllOf(append(embeds, builder.Build())...).Build()
+	}
+	return builder.Build()
+}
+
+// collectModelFields 按json标签收集字段, 规则与 collectFields 相同
+func (oa *OpenAPI) collectModelFields(t reflect.Type, depth int, optional bool, fields *[]*structField, embeds *[]*spec.RefOrSpec[spec.Schema], seen map[reflect.Type]bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag
+		if tag.Get("json") == "-" || field.Name == "XMLName" {
+			continue
+		}
+		name := tagFieldName(tag, "json")
+		ft := field.Type
+		pointer := ft.Kind() == reflect.Pointer
+		if pointer {
+			ft = ft.Elem()
+		}
+		// 未导出的嵌入结构体的导出字段同样被提升
+		if field.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			if embeds != nil && depth == 0 && field.IsExported() {
+				*embeds = append(*embeds, oa.modelSchema(ft, tag))
+				continue
+			}
+			if !seen[ft] {
+				seen[ft] = true
+				oa.collectModelFields(ft, depth+1, optional || pointer, fields, nil, seen)
+				delete(seen, ft)
+			}
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		tagged := name != ""
+		if !tagged {
+			name = field.Name
+		}
+		schema := oa.modelSchema(field.Type, tag)
+		if schema.Spec != nil {
+			typ := schemaType(schema.Spec)
+			if v := tag.Get("default"); v != "" {
+				schema.Spec.Default = typedValue(typ, v)
+			}
+			if v := tag.Get("example"); v != "" {
+				schema.Spec.Example = typedValue(typ, v)
+			}
+		}
+		rules := parseValidateRules(tag)
+		applyValidateRules(schema, rules)
+		schema = applyXMLTag(schema, tag, name)
+		*fields = append(*fields, &structField{
+			name:     name,
+			depth:    depth,
+			tagged:   tagged,
+			required: !optional && hasRequiredRule(rules) && !isOmitEmpty(tag, "json"),
+			schema:   schema,
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package fw_openapi
+
+import (
+	"strings"
+
+	"github.com/linxlib/astp/types"
+	"github.com/linxlib/conv"
+	spec "github.com/sv-tools/openapi"
+)
+
+// underlyingType 具名类型(type UserID int64)及类型别名(type Email = string)的底层类型
+func underlyingType(s *types.Struct) string {
+	if s == nil || s.IsEnum() {
+		return ""
+	}
+	return strings.TrimLeft(strings.TrimSpace(s.Type), "*")
+}
+
+// isStructType 结构体类型, 底层类型为基础类型、切片、map等的具名类型返回false
+func isStructType(s *types.Struct) bool {
+	underlying := underlyingType(s)
+	return underlying == "" || underlying == s.TypeName || strings.HasPrefix(underlying, "struct")
+}
+
+// namedTypeSchema
+// 底层类型为基础类型(或基础类型的切片)的具名类型使用底层类型的JSON类型, 并以类型的文档作为描述,
+// 其他类型返回nil
+//
+//	// UserID 用户ID
+//	type UserID int64  => {type: integer, format: int64, description: 用户ID}
+//	type Tags []string => {type: array, items: {type: string}}
+func (oa *OpenAPI) namedTypeSchema(s *types.Struct) *spec.RefOrSpec[spec.Schema] {
+	underlying := underlyingType(s)
+	if underlying == "" || underlying == s.TypeName {
+		return nil
+	}
+	var schema *spec.RefOrSpec[spec.Schema]
+	if b := primitiveSchema(underlying); b != nil {
+		schema = b.Build()
+	} else if mapped := oa.typeMapping(underlying, nil); mapped != nil {
+		schema = mapped
+	} else if elem := strings.TrimLeft(strings.TrimPrefix(underlying, "[]"), "*"); strings.HasPrefix(underlying, "[]") {
+		items := primitiveSchema(elem)
+		if items == nil {
+			return nil
+		}
+		schema = spec.NewSchemaBuilder().Type("array").Items(spec.NewBoolOrSchema(items.Build())).Build()
+	} else {
+		return nil
+	}
+	if schema.Spec != nil {
+		if doc := oa.getComment(s.Doc); doc != "" {
+			schema.Spec.Description = doc
+		}
+	}
+	return schema
+}
+
+// namedFieldSchema
+// 字段类型为具名的基础类型时, 配置了namedTypes则注册为components中的schema并引用,
+// 否则内联底层类型, 字段的注释、默认值及示例优先
+func (oa *OpenAPI) namedFieldSchema(f *types.Field, tagName string, defaultValue string, comment string, exampleValue string) *spec.RefOrSpec[spec.Schema] {
+	schema := oa.namedTypeSchema(f.Struct)
+	if schema == nil {
+		return nil
+	}
+	if oa.openApiMiddleware.Options().NamedTypes && tagName == "json" {
+		schema = withExample(oa.structSchema(f.Struct, tagName), exampleValue)
+	} else if schema.Spec != nil {
+		if comment != "" {
+			schema.Spec.Description = comment
+		}
+		if defaultValue != "" {
+			schema.Spec.Default = typedValue(schemaType(schema.Spec), defaultValue)
+		}
+		if exampleValue != "" {
+			schema.Spec.Example = typedValue(schemaType(schema.Spec), exampleValue)
+		}
+	}
+	if f.Slice {
+		return spec.NewSchemaBuilder().Type("array").Items(spec.NewBoolOrSchema(schema)).Description(comment).Build()
+	}
+	return schema
+}
+
+// typedValue 标签中的值转换为与JSON类型一致的值
+func typedValue(typ string, value string) any {
+	switch typ {
+	case "integer":
+		return conv.Int64(value)
+	case "number":
+		return conv.Float64(value)
+	case "boolean":
+		return conv.Bool(value)
+	default:
+		return value
+	}
+}
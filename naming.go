@@ -0,0 +1,251 @@
+package fw_openapi
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/linxlib/astp/types"
+	"github.com/pterm/pterm"
+	spec "github.com/sv-tools/openapi"
+)
+
+// NamingStrategy 结构体在components/schemas中的命名方式
+type NamingStrategy func(s *types.Struct) string
+
+// ShortNaming 只使用类型名, Page[User] => PageOfUser
+func ShortNaming(s *types.Struct) string {
+	return componentName(s.TypeName)
+}
+
+// PackageNaming 使用包名限定的类型名, model.Page[User] => model.PageOfUser
+func PackageNaming(s *types.Struct) string {
+	pkg := path.Base(structPackage(s))
+	if pkg == "" || pkg == "." || pkg == "/" {
+		return ShortNaming(s)
+	}
+	return pkg + "." + ShortNaming(s)
+}
+
+// SetNamingStrategy 自定义components中结构体的命名, 优先于配置中的naming
+func (oa *OpenAPI) SetNamingStrategy(strategy NamingStrategy) {
+	oa.naming = strategy
+}
+
+func (oa *OpenAPI) namingStrategy() NamingStrategy {
+	if oa.naming != nil {
+		return oa.naming
+	}
+	if oa.openApiMiddleware != nil && oa.openApiMiddleware.Options().Naming == "package" {
+		return PackageNaming
+	}
+	return ShortNaming
+}
+
+// structPackage 结构体所在包的导入路径
+func structPackage(s *types.Struct) string {
+	return s.PackagePath
+}
+
+// structIdentity 结构体的唯一标识: 包路径.类型名
+func structIdentity(s *types.Struct) string {
+	return structPackage(s) + "." + s.TypeName
+}
+
+// schemaName
+// 结构体在components/schemas中的名称, 同一结构体总是得到相同的名称,
+// 不同包的同名结构体冲突时输出警告并改用包名限定的名称
+func (oa *OpenAPI) schemaName(s *types.Struct) string {
+	identity := structIdentity(s)
+	if name, ok := oa.schemaNames[identity]; ok {
+		return name
+	}
+	if oa.schemaNames == nil {
+		oa.schemaNames = make(map[string]string)
+		oa.schemaOwners = make(map[string]string)
+	}
+	name := sanitizeName(oa.namingStrategy()(s))
+	if owner, ok := oa.schemaOwners[name]; ok && owner != identity {
+		qualified := sanitizeName(PackageNaming(s))
+		pterm.Warning.Printfln("openapi: schema name %s of %s collides with %s, %s is used instead", name, identity, owner, qualified)
+		name = qualified
+		for i := 2; ; i++ {
+			if owner, ok := oa.schemaOwners[name]; !ok || owner == identity {
+				break
+			}
+			name = fmt.Sprintf("%s%d", qualified, i)
+		}
+	}
+	oa.schemaNames[identity] = name
+	oa.schemaOwners[name] = identity
+	return name
+}
+
+var invalidNameChars = regexp.MustCompile(`[^a-zA-Z0-9._\-]`)
+
+// sanitizeName components中的名称只能包含 a-zA-Z0-9.-_
+func sanitizeName(name string) string {
+	return invalidNameChars.ReplaceAllString(name, "")
+}
+
+// componentName
+// 类型名转换为components中可读且合法的名称
+//
+//	*User              => User
+//	[]User             => User
+//	Page[User]         => PageOfUser
+//	Pair[string,*User] => PairOfStringAndUser
+//	Page[[]model.User] => PageOfUserList
+func componentName(typeName string) string {
+	t := strings.TrimSpace(typeName)
+	for strings.HasPrefix(t, "*") || strings.HasPrefix(t, "[]") {
+		t = strings.TrimPrefix(strings.TrimPrefix(t, "*"), "[]")
+	}
+	return sanitizeName(readableTypeName(t))
+}
+
+func readableTypeName(t string) string {
+	t = strings.TrimLeft(strings.TrimSpace(t), "*")
+	if strings.HasPrefix(t, "[]") {
+		return readableTypeName(t[2:]) + "List"
+	}
+	base, args, generic := strings.Cut(t, "[")
+	if i := strings.LastIndex(base, "."); i >= 0 {
+		base = base[i+1:]
+	}
+	base = capitalize(base)
+	if !generic {
+		return base
+	}
+	args = strings.TrimSuffix(args, "]")
+	names := make([]string, 0)
+	for _, arg := range splitTypeArgs(args) {
+		names = append(names, readableTypeName(arg))
+	}
+	return base + "Of" + strings.Join(names, "And")
+}
+
+// splitTypeArgs 按最外层的逗号拆分泛型参数
+func splitTypeArgs(args string) []string {
+	result := make([]string, 0)
+	depth, start := 0, 0
+	for i, c := range args {
+		switch c {
+		case '[':
+			depth++
+		case ']':
+			depth--
+		case ',':
+			if depth == 0 {
+				result = append(result, args[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(result, args[start:])
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// requireStruct 登记需要注册到分组components中的结构体(递归类型的回引), 返回其名称
+func (oa *OpenAPI) requireStruct(groupName string, s *types.Struct) string {
+	name := oa.schemaName(s)
+	if oa.pendingStructs == nil {
+		oa.pendingStructs = make(map[string]map[string]*types.Struct)
+	}
+	if oa.pendingStructs[groupName] == nil {
+		oa.pendingStructs[groupName] = make(map[string]*types.Struct)
+	}
+	oa.pendingStructs[groupName][name] = s
+	return name
+}
+
+// requireSchema
+// 按类型名(如 @Response 中声明的类型)引用schema, 类型在 WriteOut 时才解析, 届时再填写$ref
+func (oa *OpenAPI) requireSchema(groupName string, typeName string) *spec.RefOrSpec[spec.Schema] {
+	ref := &spec.Ref{Ref: "#/components/schemas/" + componentName(typeName)}
+	if oa.pendingRefs == nil {
+		oa.pendingRefs = make(map[string]map[string][]*spec.Ref)
+	}
+	if oa.pendingRefs[groupName] == nil {
+		oa.pendingRefs[groupName] = make(map[string][]*spec.Ref)
+	}
+	oa.pendingRefs[groupName][typeName] = append(oa.pendingRefs[groupName][typeName], ref)
+	return spec.NewRefOrSpec[spec.Schema](ref)
+}
+
+// resolvePendingSchemas
+// 解析按类型名的引用并将登记的结构体注册到分组的components中,
+// 生成schema的过程中可能登记新的结构体, 重复直到全部注册
+func (oa *OpenAPI) resolvePendingSchemas(groupName string, g *spec.OpenAPIBuilder) {
+	resolved := make(map[string]string)
+	for {
+		// 按类型名排序, 冲突时得到限定名称的类型在每次生成时相同
+		typeNames := make([]string, 0, len(oa.pendingRefs[groupName]))
+		for typeName := range oa.pendingRefs[groupName] {
+			typeNames = append(typeNames, typeName)
+		}
+		sort.Strings(typeNames)
+		for _, typeName := range typeNames {
+			refs := oa.pendingRefs[groupName][typeName]
+			name, ok := resolved[typeName]
+			if !ok {
+				name = componentName(typeName)
+				if s, ok := oa.lookupStruct(typeName); ok {
+					name = oa.requireStruct(groupName, s)
+				} else if t, ok := oa.lookupModel(typeName); ok {
+					name = oa.modelComponent(t)
+				} else if !hasSchema(g, name) {
+					if oa.onlyPolymorphic(refs) {
+						// @OneOf/@AnyOf 中无法解析的类型由 resolveMappings 去掉
+						pterm.Warning.Printfln("openapi: [%s] type %s of @OneOf/@AnyOf can not be resolved, register it with RegisterModels, it is dropped", groupName, typeName)
+						name = ""
+					} else {
+						pterm.Warning.Printfln("openapi: [%s] type %s can not be resolved, register it with RegisterModels", groupName, typeName)
+						g.AddComponent(name, spec.NewSchemaBuilder().Type("object").GoType(typeName).Build())
+					}
+				}
+				resolved[typeName] = name
+			}
+			for _, ref := range refs {
+				if name == "" {
+					ref.Ref = ""
+					continue
+				}
+				ref.Ref = "#/components/schemas/" + name
+			}
+		}
+		names := make([]string, 0)
+		for name := range oa.pendingStructs[groupName] {
+			if !hasSchema(g, name) {
+				names = append(names, name)
+			}
+		}
+		models := make([]string, 0)
+		for name := range oa.pendingModels[groupName] {
+			if !hasSchema(g, name) {
+				models = append(models, name)
+			}
+		}
+		if len(names) == 0 && len(models) == 0 {
+			return
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			g.AddComponent(name, oa.NewObjectProp(oa.pendingStructs[groupName][name], "json"))
+		}
+		sort.Strings(models)
+		for _, name := range models {
+			if !hasSchema(g, name) {
+				g.AddComponent(name, oa.modelComponentSchema(oa.pendingModels[groupName][name]))
+			}
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package fw_openapi
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/linxlib/astp/types"
+)
+
+func TestComponentName(t *testing.T) {
+	tests := []struct {
+		typeName string
+		want     string
+	}{
+		{typeName: "User", want: "User"},
+		{typeName: "*User", want: "User"},
+		{typeName: "[]User", want: "User"},
+		{typeName: "[]*model.User", want: "User"},
+		{typeName: "model.User", want: "User"},
+		{typeName: "user", want: "User"},
+		{typeName: "Page[User]", want: "PageOfUser"},
+		{typeName: "Page[*User]", want: "PageOfUser"},
+		{typeName: "Page[[]model.User]", want: "PageOfUserList"},
+		{typeName: "Pair[string,*User]", want: "PairOfStringAndUser"},
+		{typeName: "Pair[string, Page[User]]", want: "PairOfStringAndPageOfUser"},
+		{typeName: "model.Page[github.com/x/model.User]", want: "PageOfUser"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.typeName, func(t *testing.T) {
+			if got := componentName(tt.typeName); got != tt.want {
+				t.Errorf("componentName(%q) = %q, want %q", tt.typeName, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitTypeArgs(t *testing.T) {
+	tests := []struct {
+		args string
+		want []string
+	}{
+		{args: "User", want: []string{"User"}},
+		{args: "string,*User", want: []string{"string", "*User"}},
+		{args: "Page[A,B],C", want: []string{"Page[A,B]", "C"}},
+		{args: "Pair[Page[A],B]", want: []string{"Pair[Page[A],B]"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.args, func(t *testing.T) {
+			if got := splitTypeArgs(tt.args); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitTypeArgs(%q) = %q, want %q", tt.args, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSchemaName(t *testing.T) {
+	user := &types.Struct{Name: "User", TypeName: "User", PackagePath: "example.com/app/model"}
+	adminUser := &types.Struct{Name: "User", TypeName: "User", PackagePath: "example.com/app/admin"}
+	otherAdminUser := &types.Struct{Name: "User", TypeName: "User", PackagePath: "example.com/legacy/admin"}
+	page := &types.Struct{Name: "Page", TypeName: "Page[User]", PackagePath: "example.com/app/model"}
+	tests := []struct {
+		name     string
+		naming   NamingStrategy
+		structs  []*types.Struct
+		expected []string
+	}{
+		{
+			name:     "short names",
+			structs:  []*types.Struct{user, page},
+			expected: []string{"User", "PageOfUser"},
+		},
+		{
+			name:     "same struct gets the same name",
+			structs:  []*types.Struct{user, user},
+			expected: []string{"User", "User"},
+		},
+		{
+			name:     "collision is qualified with the package name",
+			structs:  []*types.Struct{user, adminUser},
+			expected: []string{"User", "admin.User"},
+		},
+		{
+			name:     "collision of qualified names is numbered",
+			structs:  []*types.Struct{user, adminUser, otherAdminUser},
+			expected: []string{"User", "admin.User", "admin.User2"},
+		},
+		{
+			name:     "package naming",
+			naming:   PackageNaming,
+			structs:  []*types.Struct{user, adminUser, page},
+			expected: []string{"model.User", "admin.User", "model.PageOfUser"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oa := &OpenAPI{}
+			if tt.naming != nil {
+				oa.SetNamingStrategy(tt.naming)
+			}
+			got := make([]string, 0, len(tt.structs))
+			for _, s := range tt.structs {
+				got = append(got, oa.schemaName(s))
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("schemaName() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
@@ -14,6 +14,8 @@ import (
 	"github.com/pterm/pterm"
 	spec "github.com/sv-tools/openapi"
 	"os"
+	"reflect"
+	"sort"
 	"strings"
 )
 
@@ -27,8 +29,26 @@ var innerAttrNames = map[string]attribute.AttributeType{
 	"Description":    attribute.TypeDoc,
 	"Summary":        attribute.TypeDoc,
 	"TermsOfService": attribute.TypeDoc,
+	"CookieAuth":     attribute.TypeDoc,
+	"Response":       attribute.TypeDoc,
+	"Failure":        attribute.TypeDoc,
+	"ErrorModel":     attribute.TypeDoc,
+	"Wrapper":        attribute.TypeDoc,
+	"Raw":            attribute.TypeTagger,
+	"OneOf":          attribute.TypeDoc,
+	"AnyOf":          attribute.TypeDoc,
+	"Discriminator":  attribute.TypeDoc,
+	"Schema":         attribute.TypeDoc,
+	"Example":        attribute.TypeDoc,
+	"Accept":         attribute.TypeDoc,
+	"Produce":        attribute.TypeDoc,
 }
 
+const (
+	apiKeyAuthName = "ApiKeyAuth"
+	cookieAuthName = "CookieAuth"
+)
+
 //var openApiMiddleware *middleware.OpenApiMiddleware
 
 func init() {
@@ -50,7 +70,32 @@ type OpenAPI struct {
 	openApiMiddleware *middleware.OpenApiMiddleware
 	infoBuilder       *spec.InfoBuilder
 	securityBuilder   *spec.SecuritySchemeBuilder
+	cookieAuthBuilder *spec.SecuritySchemeBuilder
 	serverBuilder     *spec.ServerBuilder
+	structs           map[string]*types.Struct
+	structIndex       map[string][]string
+	warnedAmbiguous   map[string]bool
+	models            map[string]reflect.Type
+	modelIndex        map[string][]string
+	pendingStructs    map[string]map[string]*types.Struct
+	pendingRefs       map[string]map[string][]*spec.Ref
+	pendingModels     map[string]map[string]reflect.Type
+	pendingMappings   map[string][]*pendingMapping
+	polymorphicRefs   map[*spec.Ref]bool
+	polymorphics      map[string][]*spec.Schema
+	naming            NamingStrategy
+	mappings          *typeMappings
+	schemaFiles       map[string]*spec.RefOrSpec[spec.Schema]
+	warnedProviders   map[string]bool
+	exampleGroups     map[string]bool
+	schemaNames       map[string]string
+	schemaOwners      map[string]string
+	errorModel        string
+	errorRefs         map[string][]*spec.Ref
+	wrapper           string
+	wrapTargets       map[string][]*spec.Extendable[spec.MediaType]
+	currentGroup      string
+	visiting          map[string]bool
 }
 
 func (oa *OpenAPI) getCurrentGroup(name string) *spec.OpenAPIBuilder {
@@ -150,10 +195,18 @@ func (oa *OpenAPI) HandleStructs(ctl *types.Struct) {
 	groupName := "default"
 	desc := ctl.Name
 	isDeprecated := false
+	isRaw := false
+	var accepts, produces []string
 	for _, attr := range allAttrs {
 		if attr.AttrType == constants.AT_CUSTOM {
 			if strings.ToUpper(attr.CustomAttr) == "DEPRECATED" {
 				isDeprecated = true
+			} else if strings.ToUpper(attr.CustomAttr) == "ACCEPT" {
+				accepts = parseMediaTypes(attr.AttrValue)
+			} else if strings.ToUpper(attr.CustomAttr) == "PRODUCE" {
+				produces = parseMediaTypes(attr.AttrValue)
+			} else if strings.ToUpper(attr.CustomAttr) == "RAW" {
+				isRaw = true
 			} else if strings.ToUpper(attr.CustomAttr) == "TAG" {
 				tagName = ctl.Name
 				desc = attr.AttrValue
@@ -174,6 +227,7 @@ func (oa *OpenAPI) HandleStructs(ctl *types.Struct) {
 	if tagName == "" {
 		tagName = ctl.Name
 	}
+	oa.currentGroup = groupName
 
 	oa.getCurrentGroup(groupName).AddTags(oa.NewTag(tagName, quoted(desc)))
 
@@ -181,18 +235,47 @@ func (oa *OpenAPI) HandleStructs(ctl *types.Struct) {
 		return !method.Private && method.HasAttrs()
 	}, func(method *types.Function) {
 
-		route := oa.so.BasePath
-		route = joinRoute(route, r)
-		m := ""
+		baseRoute := joinRoute(oa.so.BasePath, r)
+		routes := make([]routeMethod, 0)
 		summary := ""
 		desc := ""
 
 		isMethodDeprecated := false
+		isMethodRaw := isRaw
+		// 方法上的 @Accept/@Produce 优先于控制器上的
+		methodAccepts, methodProduces := accepts, produces
+		responseAttrs := make([]*responseAttr, 0)
+		exampleAttrs := make([]*exampleAttr, 0)
 		attrs1 := method.Doc
 		for _, a := range attrs1 {
-			if a.IsHttpMethod() {
-				m = constants.AttrNames[a.AttrType]
-				route = joinRoute(route, a.AttrValue)
+			if a.AttrType == constants.AT_CUSTOM &&
+				(strings.EqualFold(a.CustomAttr, "Response") || strings.EqualFold(a.CustomAttr, "Failure")) {
+				if ra, ok := parseResponseAttr(a.AttrValue, strings.EqualFold(a.CustomAttr, "Failure")); ok {
+					responseAttrs = append(responseAttrs, ra)
+				} else {
+					pterm.Warning.Printfln("openapi: %s.%s has invalid @%s %s", ctl.Name, method.Name, a.CustomAttr, a.AttrValue)
+				}
+			} else if a.AttrType == constants.AT_CUSTOM && strings.EqualFold(a.CustomAttr, "Accept") {
+				methodAccepts = parseMediaTypes(a.AttrValue)
+			} else if a.AttrType == constants.AT_CUSTOM && strings.EqualFold(a.CustomAttr, "Produce") {
+				methodProduces = parseMediaTypes(a.AttrValue)
+			} else if a.AttrType == constants.AT_CUSTOM && strings.EqualFold(a.CustomAttr, "Example") {
+				if ea, ok := parseExampleAttr(a.AttrValue); ok {
+					exampleAttrs = append(exampleAttrs, ea)
+				} else {
+					pterm.Warning.Printfln("openapi: %s.%s has invalid @Example %s", ctl.Name, method.Name, a.AttrValue)
+				}
+			} else if a.AttrType == constants.AT_CUSTOM && strings.EqualFold(a.CustomAttr, "Raw") {
+				// @Raw 响应不使用统一的包装结构
+				isMethodRaw = true
+			} else if a.IsHttpMethod() {
+				route := joinRoute(baseRoute, a.AttrValue)
+				if route == "" {
+					route = "/"
+				}
+				for _, m := range expandMethod(constants.AttrNames[a.AttrType]) {
+					routes = append(routes, routeMethod{method: m, route: route})
+				}
 			} else if a.IsSelf {
 				if a.AttrValue != "" {
 					summary = a.AttrValue
@@ -206,15 +289,11 @@ func (oa *OpenAPI) HandleStructs(ctl *types.Struct) {
 			}
 
 		}
-		if m == "" {
+		if len(routes) == 0 {
 			return
 		}
-		if route == "" {
-			route = "/"
-		}
 		//oa.Log("route", route)
 		//fmt.Println(route)
-		path := spec.NewPathItemBuilder()
 
 		op := spec.NewOperationBuilder()
 
@@ -223,12 +302,13 @@ func (oa *OpenAPI) HandleStructs(ctl *types.Struct) {
 		op.Description(quoted(desc))
 		op.Deprecated(isDeprecated || isMethodDeprecated)
 
-		sr := spec.NewSecurityRequirementBuilder().Add("ApiKeyAuth", "write:"+tagName, "read:"+tagName).Build()
+		sr := spec.NewSecurityRequirementBuilder().Add(apiKeyAuthName, "write:"+tagName, "read:"+tagName).Build()
 		op.Security(*sr)
 
 		op.Tags(tagName)
 
 		//params
+		var bodyStruct *types.Struct
 		method.VisitParams(func(element *types.Param) {
 			//oa.Log("params", element.TypeName)
 
@@ -240,6 +320,10 @@ func (oa *OpenAPI) HandleStructs(ctl *types.Struct) {
 
 			attr := element.Struct.GetAttr()
 			switch attr {
+			case constants.AT_BODY, constants.AT_JSON, constants.AT_XML, constants.AT_FORM, constants.AT_MULTIPART:
+				bodyStruct = element.Struct
+			}
+			switch attr {
 			case constants.AT_BODY, constants.AT_JSON:
 
 				body := spec.NewRequestBodyBuilder()
@@ -280,11 +364,10 @@ func (oa *OpenAPI) HandleStructs(ctl *types.Struct) {
 
 			case constants.AT_HEADER:
 				ps := oa.NewObjectParameters(element.Struct, "header")
-				op.Parameters(ps...)
+				op.AddParameters(ps...)
 			case constants.AT_COOKIE:
-				break
-				//ps := oa.NewObjectParameters(element.Struct, "cookie")
-				//op.AddParameters(ps...)
+				ps := oa.NewObjectParameters(element.Struct, "cookie")
+				op.AddParameters(ps...)
 			case constants.AT_XML:
 				body := spec.NewRequestBodyBuilder()
 				body.Required(true)
@@ -305,27 +388,21 @@ func (oa *OpenAPI) HandleStructs(ctl *types.Struct) {
 			}
 		})
 
-		response := spec.NewResponseBuilder()
-		errResponse := spec.NewResponseBuilder()
+		responses := make(map[string]*spec.RefOrSpec[spec.Extendable[spec.Response]])
+		var errResponse *spec.RefOrSpec[spec.Extendable[spec.Response]]
 		method.VisitResults(func(element *types.Param) {
 			//oa.Log("results", element.TypeName)
-			refName := oa.handleResults(element, groupName)
+			schema := oa.handleResults(element, groupName)
 			if element.Struct != nil {
+				response := spec.NewResponseBuilder()
 				mediaType := spec.NewMediaTypeBuilder()
-				schema := spec.NewSchemaBuilder().Type("object").Ref("#/components/schemas/" + refName).Build()
 				mediaType.Schema(schema)
 				response.Description("success").AddContent("application/json", mediaType.Build())
+				responses["200"] = response.Build()
 			} else {
 				if element.Type == "error" {
 					//oa.Log("results", "add 500")
-					mediaType := spec.NewMediaTypeBuilder()
-					errSchema := spec.NewSchemaBuilder().
-						Type("object").
-						AddProperty("code", spec.NewSchemaBuilder().Type("integer").Format("int").Example(0).Build()).
-						AddProperty("message", spec.NewSchemaBuilder().Type("string").Example("错误信息").Build()).
-						Build()
-					mediaType.Schema(errSchema)
-					errResponse.Description("fail").AddContent("application/json", mediaType.Build())
+					errResponse = oa.errorResponse(groupName, "fail")
 					return
 				}
 			}
@@ -334,43 +411,187 @@ func (oa *OpenAPI) HandleStructs(ctl *types.Struct) {
 
 		//oa.OpenAPIBuilder.AddComponent("success", response.Build())
 		op1 := op.Build()
+		op1.Spec.Parameters = mergeParameters(op1.Spec.OperationID, op1.Spec.Parameters)
+		if len(methodAccepts) > 0 && op1.Spec.RequestBody != nil && op1.Spec.RequestBody.Spec != nil {
+			body := op1.Spec.RequestBody.Spec.Spec
+			body.Content = oa.negotiateContent(body.Content, methodAccepts, bodyStruct)
+		}
+		// @Response/@Failure 声明的2xx状态码替换根据返回值推断的200
+		for _, ra := range responseAttrs {
+			if isSuccessCode(ra.code) {
+				delete(responses, "200")
+				break
+			}
+		}
+		for _, ra := range responseAttrs {
+			if ra.code == "default" {
+				errResponse = oa.newAnnotatedResponse(groupName, ra)
+				continue
+			}
+			responses[ra.code] = oa.newAnnotatedResponse(groupName, ra)
+		}
+		hasSuccess := false
+		for code := range responses {
+			if isSuccessCode(code) {
+				hasSuccess = true
+			}
+		}
+		if !hasSuccess {
+			responses["200"] = spec.NewResponseBuilder().Description("success").Build()
+		}
+		if len(methodProduces) > 0 {
+			for code, response := range responses {
+				// 直接写入context的handler(导出csv/pdf等)没有返回值, 同样按声明的类型生成content
+				if isSuccessCode(code) && code != "204" && code != "205" && response.Spec != nil {
+					response.Spec.Spec.Content = oa.negotiateContent(response.Spec.Spec.Content, methodProduces, nil)
+				}
+			}
+		}
+		if !isMethodRaw {
+			for code, response := range responses {
+				if isSuccessCode(code) {
+					oa.addWrapTarget(groupName, response)
+				}
+			}
+		}
 		op1.Spec.Responses = new(spec.Extendable[spec.Responses])
 		op1.Spec.Responses.Spec = new(spec.Responses)
-		op1.Spec.Responses.Spec.Response = map[string]*spec.RefOrSpec[spec.Extendable[spec.Response]]{
-			"200":     response.Build(),
-			"not 200": errResponse.Build(),
+		op1.Spec.Responses.Spec.Response = responses
+		// 返回error的handler未声明的错误均落到default
+		op1.Spec.Responses.Spec.Default = errResponse
+		for _, ea := range exampleAttrs {
+			oa.addExample(op1.Spec, responses, ea)
 		}
 
-		switch m {
-		case "GET":
-			path.Get(op1)
-		case "POST":
-			path.Post(op1)
-		case "PUT":
-			path.Put(op1)
-		case "DELETE":
-			path.Delete(op1)
-		case "OPTIONS":
-			path.Options(op1)
-		default:
-			path.Get(op1)
+		ids := make(map[string]int, len(routes))
+		for _, rm := range routes {
+			cp := *op1.Spec
+			if len(routes) > 1 {
+				// any/多方法路由展开为多个operation, operationId追加方法名(及序号)保证唯一
+				cp.OperationID = fmt.Sprintf("%s.%s.%s", ctl.Name, method.Name, strings.ToLower(rm.method))
+				if n := ids[cp.OperationID]; n > 0 {
+					ids[cp.OperationID]++
+					cp.OperationID = fmt.Sprintf("%s.%d", cp.OperationID, n)
+				} else {
+					ids[cp.OperationID] = 1
+				}
+			}
+			route, pathParams := normalizeRoute(rm.route)
+			cp.Parameters = oa.withPathParameters(route, cp.Parameters, pathParams)
+			ext := spec.NewExtendable(&cp)
+			ext.Extensions = op1.Extensions
+			oa.addOperation(groupName, route, rm.method, ext)
 		}
-
-		oa.builders[groupName].AddPath(route, path.Build())
 	})
 }
 
+// routeMethod 一个handler声明的http方法及路由
+type routeMethod struct {
+	method string
+	route  string
+}
+
+// httpMethods OpenAPI PathItem支持的全部方法
+var httpMethods = []string{"GET", "PUT", "POST", "DELETE", "OPTIONS", "HEAD", "PATCH", "TRACE"}
+
+// expandMethod
+// 将astp的http方法属性名转换为OpenAPI方法列表, ANY展开为全部方法, 逗号分隔的多方法逐个展开
+func expandMethod(name string) []string {
+	name = strings.ToUpper(strings.TrimSpace(name))
+	if name == "ANY" || name == "*" {
+		return httpMethods
+	}
+	ms := make([]string, 0, 1)
+	for _, m := range strings.FieldsFunc(name, func(r rune) bool { return r == ',' || r == '|' || r == ' ' }) {
+		if m == "ANY" {
+			return httpMethods
+		}
+		ms = append(ms, m)
+	}
+	return ms
+}
+
+// getPathItem
+// 获取分组中路由对应的PathItem, 不存在时创建, 保证同一路由的多个方法合并到一个PathItem中
+func (oa *OpenAPI) getPathItem(groupName string, route string) *spec.PathItem {
+	g := oa.getCurrentGroup(groupName)
+	if paths := g.Build().Spec.Paths; paths != nil {
+		if item, ok := paths.Spec.Paths[route]; ok && item.Spec != nil {
+			return item.Spec.Spec
+		}
+	}
+	item := spec.NewPathItemBuilder().Build()
+	g.AddPath(route, item)
+	return item.Spec.Spec
+}
+
+// forEachOperation 遍历分组中的全部operation
+func forEachOperation(g *spec.OpenAPIBuilder, fn func(route string, method string, op *spec.Operation)) {
+	paths := g.Build().Spec.Paths
+	if paths == nil {
+		return
+	}
+	for route, item := range paths.Spec.Paths {
+		if item.Spec == nil {
+			continue
+		}
+		for _, m := range httpMethods {
+			if op := *operationSlot(item.Spec.Spec, m); op != nil {
+				fn(route, m, op.Spec)
+			}
+		}
+	}
+}
+
+// operationSlot 返回PathItem中http方法对应的字段
+func operationSlot(item *spec.PathItem, method string) **spec.Extendable[spec.Operation] {
+	switch method {
+	case "GET":
+		return &item.Get
+	case "POST":
+		return &item.Post
+	case "PUT":
+		return &item.Put
+	case "DELETE":
+		return &item.Delete
+	case "OPTIONS":
+		return &item.Options
+	case "HEAD":
+		return &item.Head
+	case "PATCH":
+		return &item.Patch
+	case "TRACE":
+		return &item.Trace
+	default:
+		return nil
+	}
+}
+
+// addOperation
+// 将operation挂到路由对应的PathItem上, 同一方法+路由被多个handler声明时输出警告并保留先声明的
+func (oa *OpenAPI) addOperation(groupName string, route string, method string, op *spec.Extendable[spec.Operation]) {
+	if operationSlot(&spec.PathItem{}, method) == nil {
+		pterm.Warning.Printfln("openapi: [%s] %s %s can not be described by OpenAPI, %s is ignored",
+			groupName, method, route, op.Spec.OperationID)
+		return
+	}
+	slot := operationSlot(oa.getPathItem(groupName, route), method)
+	if *slot != nil {
+		pterm.Warning.Printfln("openapi: [%s] %s %s is declared by both %s and %s, %s is ignored",
+			groupName, method, route, (*slot).Spec.OperationID, op.Spec.OperationID, op.Spec.OperationID)
+		return
+	}
+	*slot = op
+}
+
 // handleParam
 // 将参数对应的类型注册到components.schemas中
 func (oa *OpenAPI) handleParam(pf *types.Param, groupName string) string {
 	if pf.Struct == nil {
 		return ""
 	}
-	name := pf.Struct.TypeName
-	name = strings.ReplaceAll(name, "[]", "")
-	name = strings.ReplaceAll(name, "[", "_")
-	name = strings.ReplaceAll(name, "]", "_")
-	name = strings.ReplaceAll(name, "*", "-")
+	oa.rememberStruct(pf.Struct)
+	name := oa.schemaName(pf.Struct)
 	attr := pf.Struct.GetAttr()
 	switch attr {
 	case constants.AT_BODY, constants.AT_JSON:
@@ -400,23 +621,22 @@ func (oa *OpenAPI) handleParam(pf *types.Param, groupName string) string {
 	return name
 }
 
-func (oa *OpenAPI) handleResults(pf *types.Param, groupName string) string {
+// handleResults
+// 将返回值对应的类型注册到components.schemas中, 返回响应使用的schema, 切片返回元素引用的数组
+func (oa *OpenAPI) handleResults(pf *types.Param, groupName string) *spec.RefOrSpec[spec.Schema] {
 	if pf.Struct == nil {
-		return ""
+		return nil
+	}
+	oa.rememberStruct(pf.Struct)
+	name := oa.schemaName(pf.Struct)
+	if !hasSchema(oa.builders[groupName], name) {
+		oa.builders[groupName].AddComponent(name, oa.NewObjectProp(pf.Struct, "json"))
 	}
-	schema := oa.NewObjectProp(pf.Struct, "json")
-	name := pf.TypeName
-	name = strings.ReplaceAll(name, "[]", "")
-	name = strings.ReplaceAll(name, "[", "_")
-	name = strings.ReplaceAll(name, "]", "_")
-	name = strings.ReplaceAll(name, "*", "-")
+	schema := spec.NewSchemaBuilder().Ref("#/components/schemas/" + name).Build()
 	if pf.Slice {
-		schema1 := spec.NewSchemaBuilder().Type("array").Items(spec.NewBoolOrSchema(schema)).Build()
-		oa.builders[groupName].AddComponent(name, schema1)
-	} else {
-		oa.builders[groupName].AddComponent(name, schema)
+		return spec.NewSchemaBuilder().Type("array").Items(spec.NewBoolOrSchema(schema)).Build()
 	}
-	return name
+	return schema
 }
 
 func (oa *OpenAPI) Print(slot string) {
@@ -474,6 +694,18 @@ func (oa *OpenAPI) HandleServerInfo(si []*types.Comment) {
 				oa.infoBuilder.Summary(attr.AttrValue)
 			case "termsofservice":
 				oa.infoBuilder.TermsOfService(attr.AttrValue)
+			case "wrapper":
+				// @Wrapper Result 成功的响应都包装在Result的data字段中
+				oa.wrapper = strings.TrimSpace(attr.AttrValue)
+			case "errormodel":
+				// @ErrorModel ErrorBody 使用ErrorBody作为错误响应的结构
+				oa.errorModel = strings.TrimSpace(attr.AttrValue)
+			case "cookieauth":
+				// @CookieAuth SESSIONID 使用名为SESSIONID的cookie认证
+				oa.cookieAuthBuilder = spec.NewSecuritySchemeBuilder()
+				oa.cookieAuthBuilder.Name(strings.TrimSpace(attr.AttrValue))
+				oa.cookieAuthBuilder.Type("apiKey")
+				oa.cookieAuthBuilder.In("cookie")
 			}
 
 		}
@@ -482,10 +714,42 @@ func (oa *OpenAPI) HandleServerInfo(si []*types.Comment) {
 }
 
 func (oa *OpenAPI) WriteOut() error {
-	for groupName, g := range oa.builders {
+	// schema的名称在分组间共享, 按分组名排序保证每次生成的结果相同
+	groupNames := make([]string, 0, len(oa.builders))
+	for groupName := range oa.builders {
+		groupNames = append(groupNames, groupName)
+	}
+	sort.Strings(groupNames)
+	for _, groupName := range groupNames {
+		g := oa.builders[groupName]
+		oa.currentGroup = groupName
+		// 包装及错误结构可能登记新的结构体, 先于待解析的引用处理
+		oa.applyWrapper(groupName, g)
+		oa.applyErrorSchema(groupName, g)
+		oa.resolvePendingSchemas(groupName, g)
+		oa.resolveMappings(groupName)
 		g.Info(oa.infoBuilder.Build())
-		g.AddComponent("ApiAuthKey", oa.securityBuilder.Build())
+		g.AddComponent(apiKeyAuthName, oa.securityBuilder.Build())
+		if oa.cookieAuthBuilder != nil {
+			g.AddComponent(cookieAuthName, oa.cookieAuthBuilder.Build())
+			// cookie认证作为header认证的备选, 任一满足即可
+			cookieReq := spec.NewSecurityRequirementBuilder().Add(cookieAuthName).Build()
+			forEachOperation(g, func(route string, method string, op *spec.Operation) {
+				if len(op.Security) > 0 {
+					op.Security = append(op.Security, *cookieReq)
+				}
+			})
+		}
 		g.Servers(oa.serverBuilder.Build())
+		if oa.openApiMiddleware.Options().SortOutput {
+			sortSpec(g)
+		}
+		if !oa.openApiMiddleware.Options().DisableExampleSynthesis {
+			synthesizeExamples(g)
+		}
+		if oa.exampleGroups[groupName] {
+			validateExamples(groupName, g)
+		}
 		bs, _ := g.Build().MarshalJSON()
 		oa.openApiMiddleware.SetDocContent(groupName, bs, "application/json")
 	}
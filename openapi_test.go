@@ -0,0 +1,32 @@
+package fw_openapi
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExpandMethod(t *testing.T) {
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{name: "GET", want: []string{"GET"}},
+		{name: "post", want: []string{"POST"}},
+		{name: " Put ", want: []string{"PUT"}},
+		{name: "ANY", want: httpMethods},
+		{name: "any", want: httpMethods},
+		{name: "*", want: httpMethods},
+		{name: "GET,POST", want: []string{"GET", "POST"}},
+		{name: "GET|POST", want: []string{"GET", "POST"}},
+		{name: "get, post delete", want: []string{"GET", "POST", "DELETE"}},
+		{name: "GET,ANY", want: httpMethods},
+		{name: "", want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := expandMethod(tt.name); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expandMethod(%q) = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package fw_openapi
+
+import (
+	"strings"
+
+	"github.com/linxlib/astp/constants"
+	"github.com/linxlib/astp/types"
+	spec "github.com/sv-tools/openapi"
+)
+
+// pendingMapping discriminator中按类型名登记的映射, 类型在 WriteOut 时解析后填写
+type pendingMapping struct {
+	discriminator *spec.Discriminator
+	key           string
+	typeName      string
+}
+
+// polymorphicAttrs 从字段注释及接口类型的文档中读取 @OneOf/@AnyOf 及 @Discriminator
+//
+//	// @OneOf CardPayment,BankPayment
+//	// @Discriminator type
+//	type Payment interface{}
+//
+//	// @OneOf card=CardPayment,bank=BankPayment
+//	Payment Payment `json:"payment"`
+func polymorphicAttrs(f *types.Field) (kind string, impls string, propertyName string) {
+	comments := make([]*types.Comment, 0)
+	if f.Struct != nil {
+		comments = append(comments, f.Struct.Doc...)
+	}
+	// 字段上的注解优先
+	comments = append(comments, f.Comment...)
+	for _, c := range comments {
+		if c.AttrType != constants.AT_CUSTOM {
+			continue
+		}
+		switch strings.ToLower(c.CustomAttr) {
+		case "oneof":
+			kind, impls = "oneOf", c.AttrValue
+		case "anyof":
+			kind, impls = "anyOf", c.AttrValue
+		case "discriminator":
+			propertyName = strings.TrimSpace(c.AttrValue)
+		}
+	}
+	return
+}
+
+// isInterfaceField 字段类型为 any/interface{}
+func isInterfaceField(f *types.Field) bool {
+	t := strings.TrimLeft(strings.TrimPrefix(f.Type, "[]"), "*")
+	return t == "any" || t == "interface{}"
+}
+
+// polymorphicSchema
+// 带 @OneOf/@AnyOf 注解的字段生成 oneOf/anyOf 及 discriminator, 没有注解的 any/interface{} 为任意值,
+// 其他字段返回nil
+func (oa *OpenAPI) polymorphicSchema(f *types.Field) *spec.RefOrSpec[spec.Schema] {
+	kind, impls, propertyName := polymorphicAttrs(f)
+	if kind == "" {
+		if isInterfaceField(f) {
+			return spec.NewSchemaBuilder().Build()
+		}
+		return nil
+	}
+	refs := make([]*spec.RefOrSpec[spec.Schema], 0)
+	var discriminator *spec.Discriminator
+	if propertyName != "" {
+		discriminator = spec.NewDiscriminatorBuilder().PropertyName(propertyName).Build()
+	}
+	for _, impl := range strings.Split(impls, ",") {
+		impl = strings.TrimSpace(impl)
+		if impl == "" {
+			continue
+		}
+		// key=Type 指定discriminator的值, 否则使用类型名
+		key, typeName, ok := strings.Cut(impl, "=")
+		if !ok {
+			typeName = key
+		}
+		key, typeName = strings.TrimSpace(key), strings.TrimSpace(typeName)
+		ref := oa.requireSchema(oa.currentGroup, typeName)
+		if oa.polymorphicRefs == nil {
+			oa.polymorphicRefs = make(map[*spec.Ref]bool)
+		}
+		oa.polymorphicRefs[ref.Ref] = true
+		refs = append(refs, ref)
+		if discriminator != nil {
+			if oa.pendingMappings == nil {
+				oa.pendingMappings = make(map[string][]*pendingMapping)
+			}
+			oa.pendingMappings[oa.currentGroup] = append(oa.pendingMappings[oa.currentGroup], &pendingMapping{
+				discriminator: discriminator,
+				key:           key,
+				typeName:      typeName,
+			})
+		}
+	}
+	builder := spec.NewSchemaBuilder()
+	if kind == "anyOf" {
+		builder.AnyOf(refs...)
+	} else {
+		builder.OneOf(refs...)
+	}
+	if discriminator != nil {
+		builder.Discriminator(discriminator)
+	}
+	schema := builder.Build()
+	if oa.polymorphics == nil {
+		oa.polymorphics = make(map[string][]*spec.Schema)
+	}
+	oa.polymorphics[oa.currentGroup] = append(oa.polymorphics[oa.currentGroup], schema.Spec)
+	return schema
+}
+
+// onlyPolymorphic 类型名只被 @OneOf/@AnyOf 引用
+func (oa *OpenAPI) onlyPolymorphic(refs []*spec.Ref) bool {
+	for _, ref := range refs {
+		if !oa.polymorphicRefs[ref] {
+			return false
+		}
+	}
+	return true
+}
+
+// resolveMappings
+// 使用解析后的$ref填写discriminator的mapping, 并从oneOf/anyOf中去掉无法解析(未填写$ref)的类型
+func (oa *OpenAPI) resolveMappings(groupName string) {
+	for _, m := range oa.pendingMappings[groupName] {
+		refs := oa.pendingRefs[groupName][m.typeName]
+		if len(refs) == 0 || refs[0].Ref == "" {
+			continue
+		}
+		if m.discriminator.Mapping == nil {
+			m.discriminator.Mapping = make(map[string]string)
+		}
+		m.discriminator.Mapping[m.key] = refs[0].Ref
+	}
+	for _, s := range oa.polymorphics[groupName] {
+		s.OneOf = resolvedRefs(s.OneOf)
+		s.AnyOf = resolvedRefs(s.AnyOf)
+		// 全部无法解析时为任意值
+		if len(s.OneOf) == 0 && len(s.AnyOf) == 0 {
+			s.Discriminator = nil
+		}
+	}
+}
+
+func resolvedRefs(schemas []*spec.RefOrSpec[spec.Schema]) []*spec.RefOrSpec[spec.Schema] {
+	if schemas == nil {
+		return nil
+	}
+	result := make([]*spec.RefOrSpec[spec.Schema], 0, len(schemas))
+	for _, schema := range schemas {
+		if schema.Ref == nil || schema.Ref.Ref != "" {
+			result = append(result, schema)
+		}
+	}
+	if len(result) == 0 {
+		return nil
+	}
+	return result
+}
@@ -0,0 +1,128 @@
+package fw_openapi
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/linxlib/astp/constants"
+	"github.com/linxlib/astp/types"
+	"github.com/pterm/pterm"
+	spec "github.com/sv-tools/openapi"
+)
+
+// OpenAPISchemaProvider
+// 通过MarshalJSON等方式自定义序列化的类型实现该接口描述其实际的JSON结构,
+// 并通过 RegisterSchemaProvider 或 RegisterModels 注册, 替代按结构体字段生成的schema.
+// 文档由解析源码生成, 方法只有在运行时才能调用, 未注册的实现只会输出警告
+//
+//	type Money struct{ cents int64 }
+//
+//	func (Money) OpenAPISchema() *spec.RefOrSpec[spec.Schema] {
+//		return spec.NewSchemaBuilder().Type("string").Pattern(`^-?\d+\.\d{2}$`).Build()
+//	}
+type OpenAPISchemaProvider interface {
+	OpenAPISchema() *spec.RefOrSpec[spec.Schema]
+}
+
+// schemaProviderMethod 实现 OpenAPISchemaProvider 的方法名
+const schemaProviderMethod = "OpenAPISchema"
+
+// RegisterSchemaProvider 注册实现了 OpenAPISchemaProvider 的类型, 按其包路径及类型名登记到类型映射中
+//
+//	oa.RegisterSchemaProvider(Money{}, BitFlags(0), (*Payload)(nil))
+func (oa *OpenAPI) RegisterSchemaProvider(providers ...OpenAPISchemaProvider) {
+	for _, p := range providers {
+		if p == nil {
+			continue
+		}
+		t := reflect.TypeOf(p)
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		// nil指针使用零值调用, 避免方法中访问接收者时panic
+		if v := reflect.ValueOf(p); v.Kind() == reflect.Pointer && v.IsNil() {
+			p = reflect.New(t).Interface().(OpenAPISchemaProvider)
+		}
+		oa.RegisterTypeMapping(t.PkgPath()+"."+t.Name(), p.OpenAPISchema())
+	}
+}
+
+// schemaProvider 类型(或其指针)实现了 OpenAPISchemaProvider 时返回其零值
+func schemaProvider(t reflect.Type) (OpenAPISchemaProvider, bool) {
+	p, ok := reflect.New(t).Interface().(OpenAPISchemaProvider)
+	return p, ok
+}
+
+// isSchemaProviderMethod 方法签名为 OpenAPISchema() *spec.RefOrSpec[spec.Schema]
+func isSchemaProviderMethod(f *types.Function) bool {
+	if f.Name != schemaProviderMethod {
+		return false
+	}
+	params, results := 0, make([]string, 0, 1)
+	f.VisitParams(func(*types.Param) {
+		params++
+	})
+	f.VisitResults(func(p *types.Param) {
+		results = append(results, strings.TrimLeft(p.Type, "*"))
+	})
+	if params != 0 || len(results) != 1 {
+		return false
+	}
+	// 导入别名不同(spec/openapi), 只比较类型名
+	return strings.Contains(results[0], "RefOrSpec[") && strings.HasSuffix(results[0], "Schema]")
+}
+
+// customSchema
+// 结构体自定义的schema: 已注册的 OpenAPISchemaProvider / 类型映射, 或文档中 @Schema 指定的JSON Schema文件
+//
+//	// Money 金额
+//	// @Schema docs/schemas/money.json
+//	type Money struct{ cents int64 }
+func (oa *OpenAPI) customSchema(s *types.Struct) *spec.RefOrSpec[spec.Schema] {
+	if schema := oa.typeMapping(s.TypeName, s); schema != nil {
+		return schema
+	}
+	for _, c := range s.Doc {
+		if c.AttrType == constants.AT_CUSTOM && strings.EqualFold(c.CustomAttr, "Schema") {
+			if schema := oa.schemaFile(strings.TrimSpace(c.AttrValue)); schema != nil {
+				return schema
+			}
+		}
+	}
+	// 解析源码只能得到方法的签名, 运行时才能调用方法, 只能提示注册
+	s.VisitMethods(isSchemaProviderMethod, func(f *types.Function) {
+		if oa.warnedProviders[s.TypeName] {
+			return
+		}
+		if oa.warnedProviders == nil {
+			oa.warnedProviders = make(map[string]bool)
+		}
+		oa.warnedProviders[s.TypeName] = true
+		pterm.Warning.Printfln("openapi: %s implements OpenAPISchemaProvider but is not registered by RegisterSchemaProvider or RegisterModels, its fields are used", s.TypeName)
+	})
+	return nil
+}
+
+// schemaFile 读取JSON Schema文件, 同一文件只读取一次
+func (oa *OpenAPI) schemaFile(file string) *spec.RefOrSpec[spec.Schema] {
+	if schema, ok := oa.schemaFiles[file]; ok {
+		return cloneSchema(schema)
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		pterm.Warning.Printfln("openapi: can not read schema file %s: %s", file, err)
+		return nil
+	}
+	schema := new(spec.RefOrSpec[spec.Schema])
+	if err := json.Unmarshal(data, schema); err != nil {
+		pterm.Warning.Printfln("openapi: schema file %s is not a valid JSON Schema: %s", file, err)
+		return nil
+	}
+	if oa.schemaFiles == nil {
+		oa.schemaFiles = make(map[string]*spec.RefOrSpec[spec.Schema])
+	}
+	oa.schemaFiles[file] = schema
+	return cloneSchema(schema)
+}
@@ -0,0 +1,394 @@
+package fw_openapi
+
+import (
+	"net/http"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/linxlib/astp/types"
+	"github.com/pterm/pterm"
+	spec "github.com/sv-tools/openapi"
+)
+
+// responseAttr 方法上的 @Response / @Failure 属性
+//
+//	@Response 201 CreatedUser "created"
+//	@Response 200 []User
+//	@Failure 404 ErrNotFound
+//	@Response 204 "no content"
+type responseAttr struct {
+	code     string
+	typeName string
+	slice    bool
+	desc     string
+	failure  bool
+}
+
+var statusCodePattern = regexp.MustCompile(`^([1-5][0-9]{2}|[1-5]XX|default)$`)
+
+// parseResponseAttr 解析 @Response/@Failure 的值, 格式为 <code> [Type] ["description"]
+func parseResponseAttr(value string, failure bool) (*responseAttr, bool) {
+	value = strings.TrimSpace(value)
+	code, rest, _ := strings.Cut(value, " ")
+	code = strings.ToUpper(code)
+	if code == "DEFAULT" {
+		code = "default"
+	}
+	if !statusCodePattern.MatchString(code) {
+		return nil, false
+	}
+	ra := &responseAttr{code: code, failure: failure}
+	rest = strings.TrimSpace(rest)
+	if rest != "" && !strings.HasPrefix(rest, `"`) {
+		ra.typeName, rest, _ = strings.Cut(rest, " ")
+		rest = strings.TrimSpace(rest)
+		if strings.HasPrefix(ra.typeName, "[]") {
+			ra.slice = true
+			ra.typeName = strings.TrimPrefix(ra.typeName, "[]")
+		}
+		ra.typeName = strings.TrimPrefix(ra.typeName, "*")
+		if ra.typeName == "-" {
+			ra.typeName = ""
+		}
+	}
+	ra.desc = strings.Trim(rest, `"`)
+	if ra.desc == "" {
+		if n, err := strconv.Atoi(code); err == nil {
+			ra.desc = http.StatusText(n)
+		}
+	}
+	if ra.desc == "" {
+		if failure {
+			ra.desc = "fail"
+		} else {
+			ra.desc = "success"
+		}
+	}
+	return ra, true
+}
+
+// isSuccessCode 2xx状态码
+func isSuccessCode(code string) bool {
+	return strings.HasPrefix(code, "2")
+}
+
+// newAnnotatedResponse 根据 @Response/@Failure 生成response, 非基础类型引用components中的schema
+func (oa *OpenAPI) newAnnotatedResponse(groupName string, ra *responseAttr) *spec.RefOrSpec[spec.Extendable[spec.Response]] {
+	if ra.typeName == "" && ra.failure {
+		return oa.errorResponse(groupName, ra.desc)
+	}
+	response := spec.NewResponseBuilder().Description(ra.desc)
+	if ra.typeName == "" {
+		return response.Build()
+	}
+	var schema *spec.RefOrSpec[spec.Schema]
+	if b := primitiveSchema(ra.typeName); b != nil {
+		schema = b.Build()
+	} else if mapped := oa.typeMapping(ra.typeName, nil); mapped != nil {
+		schema = mapped
+	} else {
+		schema = oa.requireSchema(groupName, ra.typeName)
+	}
+	if ra.slice {
+		schema = spec.NewSchemaBuilder().Type("array").Items(spec.NewBoolOrSchema(schema)).Build()
+	}
+	mediaType := spec.NewMediaTypeBuilder().Schema(schema).Build()
+	response.AddContent("application/json", mediaType)
+	return response.Build()
+}
+
+// primitiveSchema 基础类型对应的schema, 非基础类型返回nil
+func primitiveSchema(goType string) *spec.SchemaBuilder {
+	switch goType {
+	case "string":
+		return spec.NewSchemaBuilder().Type("string")
+	case "bool":
+		return spec.NewSchemaBuilder().Type("boolean")
+	case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64":
+		return spec.NewSchemaBuilder().Type("integer").Format(getFormat(goType))
+	case "float32", "float64":
+		return spec.NewSchemaBuilder().Type("number").Format(getFormat(goType))
+	case "any", "interface{}":
+		return spec.NewSchemaBuilder()
+	default:
+		return nil
+	}
+}
+
+// rememberStruct
+// 记录解析过的结构体, 供 @Response/@OneOf/@ErrorModel/@Wrapper 等按类型名引用时查找.
+// 按 包路径.类型名 记录, 同时按短名称建立索引
+func (oa *OpenAPI) rememberStruct(s *types.Struct) {
+	if s == nil {
+		return
+	}
+	if oa.structs == nil {
+		oa.structs = make(map[string]*types.Struct)
+		oa.structIndex = make(map[string][]string)
+	}
+	identity := structIdentity(s)
+	if _, ok := oa.structs[identity]; ok {
+		return
+	}
+	oa.structs[identity] = s
+	for _, short := range []string{componentName(s.TypeName), s.Name} {
+		if short == "" {
+			continue
+		}
+		ids := oa.structIndex[short]
+		if len(ids) > 0 && ids[len(ids)-1] == identity {
+			continue
+		}
+		oa.structIndex[short] = append(ids, identity)
+	}
+}
+
+// lookupStruct
+// 按类型名查找记录过的结构体, 类型名可以是 User / model.User / github.com/x/model.User / Page[User]
+func (oa *OpenAPI) lookupStruct(typeName string) (*types.Struct, bool) {
+	t := strings.TrimLeft(strings.TrimSpace(typeName), "*")
+	if s, ok := oa.structs[t]; ok {
+		return s, true
+	}
+	id, ok := oa.pickIdentity(t, oa.structIndex, func(id string) string {
+		return structPackage(oa.structs[id])
+	})
+	if !ok {
+		return nil, false
+	}
+	return oa.structs[id], true
+}
+
+// pickIdentity
+// 在短名称索引中查找类型名对应的 包路径.类型名, 类型名带包名时按包名筛选,
+// 短名称对应多个包的类型时输出警告并使用排序后的第一个
+func (oa *OpenAPI) pickIdentity(typeName string, index map[string][]string, pkgOf func(id string) string) (string, bool) {
+	base, _, _ := strings.Cut(typeName, "[")
+	pkg := ""
+	if i := strings.LastIndex(base, "."); i >= 0 {
+		pkg = base[:i]
+	}
+	ids := index[componentName(typeName)]
+	if len(ids) == 0 {
+		// 泛型类型按名称查找: Result[T] => Result
+		ids = index[componentName(base)]
+	}
+	candidates := make([]string, 0, len(ids))
+	for _, id := range ids {
+		p := pkgOf(id)
+		if pkg == "" || p == pkg || path.Base(p) == pkg {
+			candidates = append(candidates, id)
+		}
+	}
+	if len(candidates) == 0 {
+		return "", false
+	}
+	if len(candidates) > 1 {
+		sort.Strings(candidates)
+		if !oa.warnedAmbiguous[typeName] {
+			if oa.warnedAmbiguous == nil {
+				oa.warnedAmbiguous = make(map[string]bool)
+			}
+			oa.warnedAmbiguous[typeName] = true
+			pterm.Warning.Printfln("openapi: type %s is ambiguous between %s, %s is used, qualify it with the package name", typeName, strings.Join(candidates, ", "), candidates[0])
+		}
+	}
+	return candidates[0], true
+}
+
+// pluginPackage 插件内置结构(默认的错误及包装结构)命名时使用的包路径
+const pluginPackage = "github.com/linxlib/fw_openapi"
+
+// pluginStruct 插件内置结构命名时使用的结构体, 与项目中的同名结构体冲突时改用包名限定的名称
+func pluginStruct(name string) *types.Struct {
+	return &types.Struct{Name: name, TypeName: name, PackagePath: pluginPackage}
+}
+
+// errorResponse 引用components中错误结构的response, 错误结构在 WriteOut 时确定后再填写$ref
+func (oa *OpenAPI) errorResponse(groupName string, desc string) *spec.RefOrSpec[spec.Extendable[spec.Response]] {
+	if oa.errorRefs == nil {
+		oa.errorRefs = make(map[string][]*spec.Ref)
+	}
+	ref := &spec.Ref{Ref: "#/components/schemas/Error"}
+	oa.errorRefs[groupName] = append(oa.errorRefs[groupName], ref)
+	mediaType := spec.NewMediaTypeBuilder().Schema(spec.NewRefOrSpec[spec.Schema](ref)).Build()
+	return spec.NewResponseBuilder().Description(desc).AddContent("application/json", mediaType).Build()
+}
+
+// applyErrorSchema 分组中有错误响应时登记错误结构并填写$ref
+func (oa *OpenAPI) applyErrorSchema(groupName string, g *spec.OpenAPIBuilder) {
+	if len(oa.errorRefs[groupName]) == 0 {
+		return
+	}
+	name := oa.errorComponent(g)
+	for _, ref := range oa.errorRefs[groupName] {
+		ref.Ref = "#/components/schemas/" + name
+	}
+}
+
+// errorComponent
+// 错误响应的结构, 优先使用 @ErrorModel 或配置 errorModel 指定的类型(解析过的结构体或 RegisterModels 注册的类型),
+// 其次是配置 errorFields 声明的字段, 都没有时为 {code, message}. 返回其在components中的名称
+func (oa *OpenAPI) errorComponent(g *spec.OpenAPIBuilder) string {
+	options := oa.openApiMiddleware.Options()
+	model := oa.errorModel
+	if model == "" {
+		model = options.ErrorModel
+	}
+	if model != "" {
+		if s, ok := oa.lookupStruct(model); ok {
+			name := oa.schemaName(s)
+			if !hasSchema(g, name) {
+				g.AddComponent(name, oa.NewObjectProp(s, "json"))
+			}
+			return name
+		}
+		if t, ok := oa.lookupModel(model); ok {
+			return oa.modelComponent(t)
+		}
+		pterm.Warning.Printfln("openapi: error model %s can not be resolved, register it with RegisterModels, the default one is used", model)
+	}
+	name := oa.schemaName(pluginStruct("Error"))
+	if hasSchema(g, name) {
+		return name
+	}
+	if len(options.ErrorFields) > 0 {
+		g.AddComponent(name, fieldsSchema(options.ErrorFields))
+		return name
+	}
+	g.AddComponent(name, spec.NewSchemaBuilder().
+		Type("object").
+		AddProperty("code", spec.NewSchemaBuilder().Type("integer").Format("int32").Example(0).Build()).
+		AddProperty("message", spec.NewSchemaBuilder().Type("string").Example("错误信息").Build()).
+		Build())
+	return name
+}
+
+// fieldsSchema 由配置中声明的 字段名 -> 类型(go或json类型) 生成object schema
+func fieldsSchema(fields map[string]string) *spec.RefOrSpec[spec.Schema] {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	builder := spec.NewSchemaBuilder().Type("object")
+	for _, name := range names {
+		typ := fields[name]
+		if b := primitiveSchema(typ); b != nil {
+			builder.AddProperty(name, b.Build())
+		} else {
+			builder.AddProperty(name, spec.NewSchemaBuilder().Type(typ).Build())
+		}
+	}
+	return builder.Build()
+}
+
+// addWrapTarget 记录需要包装的成功响应
+func (oa *OpenAPI) addWrapTarget(groupName string, response *spec.RefOrSpec[spec.Extendable[spec.Response]]) {
+	if response.Spec == nil {
+		return
+	}
+	if oa.wrapTargets == nil {
+		oa.wrapTargets = make(map[string][]*spec.Extendable[spec.MediaType])
+	}
+	for contentType, mediaType := range response.Spec.Spec.Content {
+		// 文件等非结构化的响应不包装
+		if mediaType.Spec.Schema != nil && isStructuredMediaType(contentType) {
+			oa.wrapTargets[groupName] = append(oa.wrapTargets[groupName], mediaType)
+		}
+	}
+}
+
+// applyWrapper
+// 配置了 @Wrapper 或 wrapper/wrapperFields 时, 将成功响应改为 包装结构 + data字段指向原结构
+//
+//	allOf:
+//	  - $ref: '#/components/schemas/Result'
+//	  - type: object
+//	    properties:
+//	      data:
+//	        $ref: '#/components/schemas/User'
+func (oa *OpenAPI) applyWrapper(groupName string, g *spec.OpenAPIBuilder) {
+	options := oa.openApiMiddleware.Options()
+	model := oa.wrapper
+	if model == "" {
+		model = options.Wrapper
+	}
+	if model == "" && len(options.WrapperFields) == 0 {
+		return
+	}
+	dataField := options.WrapperDataField
+	if dataField == "" {
+		dataField = "data"
+	}
+	ref := spec.NewSchemaBuilder().Ref("#/components/schemas/" + oa.wrapperComponent(g, model, dataField)).Build()
+	for _, mediaType := range oa.wrapTargets[groupName] {
+		data := spec.NewSchemaBuilder().Type("object").AddProperty(dataField, mediaType.Spec.Schema).Build()
+		mediaType.Spec.Schema = spec.NewSchemaBuilder().AllOf(ref, data).Build()
+	}
+}
+
+// wrapperComponent
+// 登记包装结构并返回其在components中的名称.
+// 泛型的包装结构(Result[T])以去掉data字段后的结构注册为 Result, 非泛型的结构体直接引用其自身的schema,
+// 找不到时使用配置 wrapperFields 声明的字段或 {code, msg}
+func (oa *OpenAPI) wrapperComponent(g *spec.OpenAPIBuilder, model string, dataField string) string {
+	if model != "" {
+		base, _, _ := strings.Cut(model, "[")
+		if s, ok := oa.lookupStruct(model); ok {
+			if !strings.Contains(s.TypeName, "[") {
+				name := oa.schemaName(s)
+				if !hasSchema(g, name) {
+					g.AddComponent(name, oa.NewObjectProp(s, "json"))
+				}
+				return name
+			}
+			// 按泛型结构体本身(而非某个实例化)命名
+			name := oa.schemaName(&types.Struct{Name: s.Name, TypeName: componentName(base), PackagePath: structPackage(s)})
+			if !hasSchema(g, name) {
+				g.AddComponent(name, withoutProperty(oa.NewObjectProp(s, "json"), dataField))
+			}
+			return name
+		}
+		if t, ok := oa.lookupModel(model); ok {
+			if !strings.Contains(t.Name(), "[") {
+				return oa.modelComponent(t)
+			}
+			name := oa.schemaName(&types.Struct{Name: componentName(base), TypeName: componentName(base), PackagePath: t.PkgPath()})
+			if !hasSchema(g, name) {
+				g.AddComponent(name, withoutProperty(oa.modelComponentSchema(t), dataField))
+			}
+			return name
+		}
+		pterm.Warning.Printfln("openapi: response wrapper %s can not be resolved, register it with RegisterModels, {code, msg} is used", model)
+	}
+	name := oa.schemaName(pluginStruct("Result"))
+	if hasSchema(g, name) {
+		return name
+	}
+	if len(oa.openApiMiddleware.Options().WrapperFields) > 0 {
+		g.AddComponent(name, fieldsSchema(oa.openApiMiddleware.Options().WrapperFields))
+	} else {
+		g.AddComponent(name, fieldsSchema(map[string]string{"code": "int", "msg": "string"}))
+	}
+	return name
+}
+
+// withoutProperty 去掉object schema中的字段, 包括其必填声明
+func withoutProperty(schema *spec.RefOrSpec[spec.Schema], property string) *spec.RefOrSpec[spec.Schema] {
+	if schema.Spec == nil {
+		return schema
+	}
+	delete(schema.Spec.Properties, property)
+	required := schema.Spec.Required[:0]
+	for _, r := range schema.Spec.Required {
+		if r != property {
+			required = append(required, r)
+		}
+	}
+	schema.Spec.Required = required
+	return schema
+}
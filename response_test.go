@@ -0,0 +1,63 @@
+package fw_openapi
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseResponseAttr(t *testing.T) {
+	tests := []struct {
+		value   string
+		failure bool
+		want    *responseAttr
+	}{
+		{
+			value: `201 CreatedUser "created"`,
+			want:  &responseAttr{code: "201", typeName: "CreatedUser", desc: "created"},
+		},
+		{
+			value: "200 []User",
+			want:  &responseAttr{code: "200", typeName: "User", slice: true, desc: "OK"},
+		},
+		{
+			value: "200 *model.User",
+			want:  &responseAttr{code: "200", typeName: "model.User", desc: "OK"},
+		},
+		{
+			value:   "404 ErrNotFound",
+			failure: true,
+			want:    &responseAttr{code: "404", typeName: "ErrNotFound", desc: "Not Found", failure: true},
+		},
+		{
+			value: `204 "no content"`,
+			want:  &responseAttr{code: "204", desc: "no content"},
+		},
+		{
+			value: `200 - "plain"`,
+			want:  &responseAttr{code: "200", desc: "plain"},
+		},
+		{
+			value: "4xx",
+			want:  &responseAttr{code: "4XX", desc: "success"},
+		},
+		{
+			value:   "DEFAULT Error",
+			failure: true,
+			want:    &responseAttr{code: "default", typeName: "Error", desc: "fail", failure: true},
+		},
+		{value: "600 User"},
+		{value: "ok User"},
+		{value: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, ok := parseResponseAttr(tt.value, tt.failure)
+			if ok != (tt.want != nil) {
+				t.Fatalf("parseResponseAttr(%q) ok = %v, want %v", tt.value, ok, tt.want != nil)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseResponseAttr(%q) = %+v, want %+v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
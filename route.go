@@ -0,0 +1,146 @@
+package fw_openapi
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pterm/pterm"
+	spec "github.com/sv-tools/openapi"
+)
+
+// pathParam 路由中声明的路径参数
+type pathParam struct {
+	name     string
+	pattern  string // 正则约束, 已转换为完整匹配
+	catchAll bool   // 匹配剩余的全部路径
+}
+
+// normalizeRoute
+// 将fw(fasthttp router)的路由语法转换为OpenAPI的路径模板, 同时返回模板中的路径参数
+//
+//	/users/:id          -> /users/{id}
+//	/users/{id:[0-9]+}  -> /users/{id}     pattern: ^(?:[0-9]+)$
+//	/static/{path:*}    -> /static/{path}  catch-all
+//	/files/*filepath    -> /files/{filepath} catch-all
+func normalizeRoute(route string) (string, []*pathParam) {
+	var b strings.Builder
+	params := make([]*pathParam, 0)
+	segStart := true
+	for i := 0; i < len(route); i++ {
+		c := route[i]
+		switch {
+		case segStart && (c == ':' || c == '*'):
+			j := i + 1
+			for j < len(route) && route[j] != '/' {
+				j++
+			}
+			p := &pathParam{name: route[i+1 : j], catchAll: c == '*'}
+			// OpenAPI的路径参数总是必填, 可选标记(name?)直接去掉
+			p.name = strings.TrimSuffix(p.name, "?")
+			if p.name == "" {
+				p.name = "path"
+			}
+			params = append(params, p)
+			b.WriteString("{" + p.name + "}")
+			i = j - 1
+		case c == '{':
+			// 约束中可能包含{n}这样的量词, 需要找到匹配的右括号
+			depth, j := 0, i
+			for ; j < len(route); j++ {
+				if route[j] == '{' {
+					depth++
+				} else if route[j] == '}' {
+					depth--
+					if depth == 0 {
+						break
+					}
+				}
+			}
+			if j == len(route) {
+				b.WriteString(route[i:])
+				i = j
+				break
+			}
+			name, constraint, _ := strings.Cut(route[i+1:j], ":")
+			p := &pathParam{name: name}
+			// OpenAPI的路径参数总是必填, 可选标记(name?)直接去掉
+			p.name = strings.TrimSuffix(p.name, "?")
+			if constraint == "*" {
+				p.catchAll = true
+			} else if constraint != "" {
+				p.pattern = fmt.Sprintf("^(?:%s)$", constraint)
+			}
+			params = append(params, p)
+			b.WriteString("{" + p.name + "}")
+			i = j
+		default:
+			b.WriteByte(c)
+		}
+		segStart = c == '/'
+	}
+	return b.String(), params
+}
+
+// withPathParameters
+// 保证路径模板中的每个参数都有对应的 in: path 参数,
+// 已声明的参数补充required及pattern, 未声明的补充为string类型参数, 不在模板中的路径参数被移除
+func (oa *OpenAPI) withPathParameters(route string, params []*spec.RefOrSpec[spec.Extendable[spec.Parameter]], pathParams []*pathParam) []*spec.RefOrSpec[spec.Extendable[spec.Parameter]] {
+	result := make([]*spec.RefOrSpec[spec.Extendable[spec.Parameter]], 0, len(params)+len(pathParams))
+	declared := make(map[string]bool, len(pathParams))
+	for _, p := range params {
+		if p.Spec == nil || p.Spec.Spec.In != "path" {
+			result = append(result, p)
+			continue
+		}
+		pp := findPathParam(pathParams, p.Spec.Spec.Name)
+		if pp == nil {
+			pterm.Warning.Printfln("openapi: path parameter %s is not part of route %s, ignored", p.Spec.Spec.Name, route)
+			continue
+		}
+		declared[pp.name] = true
+		param := *p.Spec.Spec
+		param.Required = true
+		if param.Schema != nil && param.Schema.Spec != nil && pp.pattern != "" {
+			schema := *param.Schema.Spec
+			schema.Pattern = pp.pattern
+			param.Schema = spec.NewRefOrSpec[spec.Schema](&schema)
+		}
+		ext := spec.NewExtendable(&param)
+		for k, v := range p.Spec.Extensions {
+			ext.Extensions[k] = v
+		}
+		if pp.catchAll {
+			ext.AddExt("catch-all", true)
+		}
+		result = append(result, spec.NewRefOrSpec[spec.Extendable[spec.Parameter]](ext))
+	}
+	for _, pp := range pathParams {
+		if declared[pp.name] {
+			continue
+		}
+		declared[pp.name] = true
+		schema := spec.NewSchemaBuilder().Type("string")
+		if pp.pattern != "" {
+			schema.Pattern(pp.pattern)
+		}
+		builder := spec.NewParameterBuilder().
+			Name(pp.name).
+			In("path").
+			Required(true).
+			Schema(schema.Build())
+		if pp.catchAll {
+			builder.Description("matches the rest of the path").AddExt("catch-all", true)
+		}
+		result = append(result, builder.Build())
+	}
+	return result
+}
+
+func findPathParam(pathParams []*pathParam, name string) *pathParam {
+	for _, pp := range pathParams {
+		if pp.name == name {
+			return pp
+		}
+	}
+	return nil
+}
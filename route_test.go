@@ -0,0 +1,99 @@
+package fw_openapi
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeRoute(t *testing.T) {
+	tests := []struct {
+		name   string
+		route  string
+		path   string
+		params []*pathParam
+	}{
+		{
+			name:   "static route",
+			route:  "/users",
+			path:   "/users",
+			params: []*pathParam{},
+		},
+		{
+			name:   "colon parameter",
+			route:  "/users/:id",
+			path:   "/users/{id}",
+			params: []*pathParam{{name: "id"}},
+		},
+		{
+			name:   "optional colon parameter",
+			route:  "/users/:id?",
+			path:   "/users/{id}",
+			params: []*pathParam{{name: "id"}},
+		},
+		{
+			name:   "several parameters",
+			route:  "/users/:uid/posts/:pid",
+			path:   "/users/{uid}/posts/{pid}",
+			params: []*pathParam{{name: "uid"}, {name: "pid"}},
+		},
+		{
+			name:   "braced parameter with constraint",
+			route:  "/users/{id:[0-9]+}",
+			path:   "/users/{id}",
+			params: []*pathParam{{name: "id", pattern: "^(?:[0-9]+)$"}},
+		},
+		{
+			name:   "constraint with nested quantifiers",
+			route:  "/orders/{code:[A-Z]{2}-[0-9]{4,6}}/items",
+			path:   "/orders/{code}/items",
+			params: []*pathParam{{name: "code", pattern: "^(?:[A-Z]{2}-[0-9]{4,6})$"}},
+		},
+		{
+			name:   "optional braced parameter",
+			route:  "/users/{id?}",
+			path:   "/users/{id}",
+			params: []*pathParam{{name: "id"}},
+		},
+		{
+			name:   "braced catch-all",
+			route:  "/static/{path:*}",
+			path:   "/static/{path}",
+			params: []*pathParam{{name: "path", catchAll: true}},
+		},
+		{
+			name:   "star catch-all",
+			route:  "/files/*filepath",
+			path:   "/files/{filepath}",
+			params: []*pathParam{{name: "filepath", catchAll: true}},
+		},
+		{
+			name:   "unnamed star catch-all",
+			route:  "/assets/*",
+			path:   "/assets/{path}",
+			params: []*pathParam{{name: "path", catchAll: true}},
+		},
+		{
+			name:   "colon inside a segment is kept",
+			route:  "/v1/users:batchGet",
+			path:   "/v1/users:batchGet",
+			params: []*pathParam{},
+		},
+		{
+			name:   "unbalanced brace is kept",
+			route:  "/users/{id",
+			path:   "/users/{id",
+			params: []*pathParam{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, params := normalizeRoute(tt.route)
+			if path != tt.path {
+				t.Errorf("normalizeRoute(%q) path = %q, want %q", tt.route, path, tt.path)
+			}
+			if !reflect.DeepEqual(params, tt.params) {
+				t.Errorf("normalizeRoute(%q) params = %+v, want %+v", tt.route, params, tt.params)
+			}
+		})
+	}
+}
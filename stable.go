@@ -0,0 +1,91 @@
+package fw_openapi
+
+import (
+	"sort"
+
+	spec "github.com/sv-tools/openapi"
+)
+
+// sortSpec
+// 配置了sortOutput时对文档中的数组排序, 使生成的JSON逐字节一致.
+// paths、properties、components等对象按key输出, encoding/json本身已有序,
+// 需要排序的是按源码遍历顺序生成的数组: tags、参数、required
+func sortSpec(g *spec.OpenAPIBuilder) {
+	doc := g.Build().Spec
+	sort.SliceStable(doc.Tags, func(i, j int) bool {
+		return doc.Tags[i].Spec.Name < doc.Tags[j].Spec.Name
+	})
+	visited := make(map[*spec.Schema]bool)
+	if doc.Components != nil {
+		for _, schema := range doc.Components.Spec.Schemas {
+			sortSchema(schema, visited)
+		}
+	}
+	forEachOperation(g, func(route string, method string, op *spec.Operation) {
+		sortParameters(op.Parameters)
+		for _, p := range op.Parameters {
+			if p.Spec != nil {
+				sortSchema(p.Spec.Spec.Schema, visited)
+			}
+		}
+		if op.RequestBody != nil && op.RequestBody.Spec != nil {
+			sortContent(op.RequestBody.Spec.Spec.Content, visited)
+		}
+		if op.Responses == nil {
+			return
+		}
+		if op.Responses.Spec.Default != nil && op.Responses.Spec.Default.Spec != nil {
+			sortContent(op.Responses.Spec.Default.Spec.Spec.Content, visited)
+		}
+		for _, response := range op.Responses.Spec.Response {
+			if response.Spec != nil {
+				sortContent(response.Spec.Spec.Content, visited)
+			}
+		}
+	})
+}
+
+// sortParameters 参数按 in, name 排序
+func sortParameters(params []*spec.RefOrSpec[spec.Extendable[spec.Parameter]]) {
+	key := func(p *spec.RefOrSpec[spec.Extendable[spec.Parameter]]) string {
+		if p.Spec == nil {
+			return p.Ref.Ref
+		}
+		return p.Spec.Spec.In + ":" + p.Spec.Spec.Name
+	}
+	sort.SliceStable(params, func(i, j int) bool {
+		return key(params[i]) < key(params[j])
+	})
+}
+
+func sortContent(content map[string]*spec.Extendable[spec.MediaType], visited map[*spec.Schema]bool) {
+	for _, mediaType := range content {
+		sortSchema(mediaType.Spec.Schema, visited)
+	}
+}
+
+// sortSchema 递归排序schema中的required
+func sortSchema(schema *spec.RefOrSpec[spec.Schema], visited map[*spec.Schema]bool) {
+	if schema == nil || schema.Spec == nil || visited[schema.Spec] {
+		return
+	}
+	s := schema.Spec
+	visited[s] = true
+	sort.Strings(s.Required)
+	for _, p := range s.Properties {
+		sortSchema(p, visited)
+	}
+	for _, list := range [][]*spec.RefOrSpec[spec.Schema]{s.AllOf, s.AnyOf, s.OneOf, s.PrefixItems} {
+		for _, item := range list {
+			sortSchema(item, visited)
+		}
+	}
+	if s.Items != nil {
+		sortSchema(s.Items.Schema, visited)
+	}
+	if s.AdditionalProperties != nil {
+		sortSchema(s.AdditionalProperties.Schema, visited)
+	}
+	sortSchema(s.Not, visited)
+	sortSchema(s.PropertyNames, visited)
+}
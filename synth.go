@@ -0,0 +1,271 @@
+package fw_openapi
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	spec "github.com/sv-tools/openapi"
+)
+
+// exampleSynthesizer 由schema生成示例, 引用的schema从components中查找
+type exampleSynthesizer struct {
+	schemas map[string]*spec.RefOrSpec[spec.Schema]
+	// 正在展开的$ref, 递归类型再次引用自身时不再展开
+	visiting map[string]bool
+}
+
+// synthesizeExamples
+// 为没有示例的请求body及响应生成示例, 写入媒体类型的example, 已有example/examples的不覆盖
+func synthesizeExamples(g *spec.OpenAPIBuilder) {
+	doc := g.Build().Spec
+	synth := &exampleSynthesizer{visiting: make(map[string]bool)}
+	if doc.Components != nil {
+		synth.schemas = doc.Components.Spec.Schemas
+	}
+	fill := func(content map[string]*spec.Extendable[spec.MediaType]) {
+		for _, mediaType := range content {
+			if mediaType.Spec.Schema == nil || mediaType.Spec.Example != nil || len(mediaType.Spec.Examples) > 0 {
+				continue
+			}
+			if example := synth.example(mediaType.Spec.Schema, ""); example != nil {
+				mediaType.Spec.Example = example
+			}
+		}
+	}
+	forEachOperation(g, func(route string, method string, op *spec.Operation) {
+		if op.RequestBody != nil && op.RequestBody.Spec != nil {
+			fill(op.RequestBody.Spec.Spec.Content)
+		}
+		if op.Responses == nil {
+			return
+		}
+		fill(responseContent(op.Responses.Spec.Default))
+		for _, response := range op.Responses.Spec.Response {
+			fill(responseContent(response))
+		}
+	})
+}
+
+// example 生成schema的示例, name为属性名, 用于按名称推断(email、phone等), 无法生成时返回nil
+func (e *exampleSynthesizer) example(schema *spec.RefOrSpec[spec.Schema], name string) any {
+	if schema == nil {
+		return nil
+	}
+	if schema.Ref != nil {
+		ref := schema.Ref.Ref
+		target, ok := e.schemas[strings.TrimPrefix(ref, "#/components/schemas/")]
+		if !ok || e.visiting[ref] {
+			return nil
+		}
+		e.visiting[ref] = true
+		defer delete(e.visiting, ref)
+		return e.example(target, name)
+	}
+	s := schema.Spec
+	switch {
+	case s.Example != nil:
+		return s.Example
+	case len(s.Examples) > 0:
+		return s.Examples[0]
+	case s.Default != nil:
+		return s.Default
+	case s.Const != "":
+		return s.Const
+	case len(s.Enum) > 0:
+		return s.Enum[0]
+	case len(s.AllOf) > 0:
+		// allOf的各部分都是对象时合并属性
+		merged := make(map[string]any)
+		for _, part := range s.AllOf {
+			v := e.example(part, name)
+			m, ok := v.(map[string]any)
+			if !ok {
+				return v
+			}
+			for k, item := range m {
+				merged[k] = item
+			}
+		}
+		return merged
+	}
+	for _, alternatives := range [][]*spec.RefOrSpec[spec.Schema]{s.OneOf, s.AnyOf} {
+		for _, alt := range alternatives {
+			if alt.Spec != nil && schemaType(alt.Spec) == "" && alt.Spec.Type != nil {
+				// {type: null}
+				continue
+			}
+			if v := e.example(alt, name); v != nil {
+				return v
+			}
+		}
+	}
+	switch schemaType(s) {
+	case "object":
+		return e.objectExample(s)
+	case "array":
+		return e.arrayExample(s, name)
+	case "string":
+		return stringExample(s, name)
+	case "integer":
+		return integerExample(s, name)
+	case "number":
+		return numberExample(s)
+	case "boolean":
+		return true
+	}
+	if len(s.Properties) > 0 {
+		return e.objectExample(s)
+	}
+	return nil
+}
+
+func (e *exampleSynthesizer) objectExample(s *spec.Schema) any {
+	result := make(map[string]any, len(s.Properties))
+	names := make([]string, 0, len(s.Properties))
+	for name := range s.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if v := e.example(s.Properties[name], name); v != nil {
+			result[name] = v
+		}
+	}
+	if len(s.Properties) == 0 && s.AdditionalProperties != nil && s.AdditionalProperties.Schema != nil {
+		if v := e.example(s.AdditionalProperties.Schema, ""); v != nil {
+			result["key"] = v
+		}
+	}
+	return result
+}
+
+// arrayExample 按minItems生成元素, 未指定时生成一个元素
+func (e *exampleSynthesizer) arrayExample(s *spec.Schema, name string) any {
+	size := 1
+	if s.MinItems != nil && *s.MinItems > size {
+		size = *s.MinItems
+	}
+	if s.MaxItems != nil && *s.MaxItems < size {
+		size = *s.MaxItems
+	}
+	result := make([]any, 0, size)
+	if s.Items == nil || s.Items.Schema == nil {
+		return result
+	}
+	for i := 0; i < size; i++ {
+		v := e.example(s.Items.Schema, name)
+		if v == nil {
+			break
+		}
+		result = append(result, v)
+	}
+	return result
+}
+
+// stringExample 按format、属性名推断示例, 并满足长度约束
+func stringExample(s *spec.Schema, name string) any {
+	var v string
+	switch s.Format {
+	case "date-time":
+		v = exampleTime.Format(time.RFC3339)
+	case "date":
+		v = exampleTime.Format(time.DateOnly)
+	case "time":
+		v = exampleTime.Format(time.TimeOnly)
+	case "email":
+		v = "user@example.com"
+	case "uri", "url":
+		v = "https://example.com"
+	case "uuid":
+		v = "3fa85f64-5717-4562-b3fc-2c963f66afa6"
+	case "ipv4":
+		v = "192.168.1.1"
+	case "ipv6":
+		v = "2001:db8::1"
+	case "hostname":
+		v = "example.com"
+	case "byte":
+		v = "ZXhhbXBsZQ=="
+	case "binary":
+		v = ""
+	default:
+		v = nameExample(name)
+	}
+	if s.MinLength != nil {
+		for len([]rune(v)) < *s.MinLength {
+			v += "x"
+		}
+	}
+	if s.MaxLength != nil && len([]rune(v)) > *s.MaxLength {
+		v = string([]rune(v)[:*s.MaxLength])
+	}
+	return v
+}
+
+// nameExample 按属性名推断字符串示例
+func nameExample(name string) string {
+	n := strings.ToLower(name)
+	switch {
+	case n == "":
+		return "string"
+	case strings.Contains(n, "email") || strings.Contains(n, "mail"):
+		return "user@example.com"
+	case strings.Contains(n, "phone") || strings.Contains(n, "mobile") || strings.HasSuffix(n, "tel"):
+		return "13800138000"
+	case strings.Contains(n, "avatar") || strings.Contains(n, "image") || strings.Contains(n, "photo") || strings.Contains(n, "picture"):
+		return "https://example.com/image.png"
+	case strings.Contains(n, "url") || strings.Contains(n, "link") || strings.Contains(n, "website"):
+		return "https://example.com"
+	case strings.Contains(n, "password") || strings.Contains(n, "pwd"):
+		return "P@ssw0rd"
+	case n == "ip" || strings.HasSuffix(n, "_ip") || strings.HasSuffix(n, "ip_addr"):
+		return "192.168.1.1"
+	}
+	return name
+}
+
+// integerExample 在最小值/最大值范围内, 按属性名推断(分页参数等)
+func integerExample(s *spec.Schema, name string) any {
+	v := 1
+	switch n := strings.ToLower(name); {
+	case n == "age":
+		v = 18
+	case n == "size" || n == "limit" || strings.HasSuffix(n, "pagesize") || strings.HasSuffix(n, "page_size"):
+		v = 10
+	}
+	return clamp(s, v)
+}
+
+func numberExample(s *spec.Schema) any {
+	v := 1.5
+	if s.Minimum != nil && v < float64(*s.Minimum) {
+		v = float64(*s.Minimum)
+	}
+	if s.ExclusiveMinimum != nil && v <= float64(*s.ExclusiveMinimum) {
+		v = float64(*s.ExclusiveMinimum) + 0.5
+	}
+	if s.Maximum != nil && v > float64(*s.Maximum) {
+		v = float64(*s.Maximum)
+	}
+	if s.ExclusiveMaximum != nil && v >= float64(*s.ExclusiveMaximum) {
+		v = float64(*s.ExclusiveMaximum) - 0.5
+	}
+	return v
+}
+
+func clamp(s *spec.Schema, v int) int {
+	if s.Minimum != nil && v < *s.Minimum {
+		v = *s.Minimum
+	}
+	if s.ExclusiveMinimum != nil && v <= *s.ExclusiveMinimum {
+		v = *s.ExclusiveMinimum + 1
+	}
+	if s.Maximum != nil && v > *s.Maximum {
+		v = *s.Maximum
+	}
+	if s.ExclusiveMaximum != nil && v >= *s.ExclusiveMaximum {
+		v = *s.ExclusiveMaximum - 1
+	}
+	return v
+}
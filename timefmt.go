@@ -0,0 +1,182 @@
+package fw_openapi
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/linxlib/astp/types"
+	"github.com/linxlib/conv"
+	spec "github.com/sv-tools/openapi"
+)
+
+// namedLayouts time_format 中可以使用的time包中布局常量的名称
+var namedLayouts = map[string]string{
+	"RFC3339":     time.RFC3339,
+	"RFC3339Nano": time.RFC3339Nano,
+	"DateTime":    time.DateTime,
+	"DateOnly":    time.DateOnly,
+	"TimeOnly":    time.TimeOnly,
+	"RFC1123":     time.RFC1123,
+	"RFC1123Z":    time.RFC1123Z,
+	"RFC822":      time.RFC822,
+	"RFC822Z":     time.RFC822Z,
+	"Kitchen":     time.Kitchen,
+}
+
+// isTimeType time.Time / *time.Time
+func isTimeType(f *types.Field) bool {
+	return isTimePackageType(f, "Time")
+}
+
+// isDurationType time.Duration / *time.Duration
+func isDurationType(f *types.Field) bool {
+	return isTimePackageType(f, "Duration")
+}
+
+// isTimePackageType
+// 字段类型为time包中的name, 解析到带包路径的类型时按包路径判断,
+// 否则按类型名(Time/time.Time)判断
+func isTimePackageType(f *types.Field, name string) bool {
+	if f.Struct != nil && structPackage(f.Struct) != "" {
+		return structPackage(f.Struct) == "time" && strings.TrimLeft(f.Struct.TypeName, "*") == name
+	}
+	t := strings.TrimLeft(strings.TrimPrefix(f.Type, "[]"), "*")
+	return t == name || t == "time."+name
+}
+
+// timeFieldSchema
+// 时间字段按 time_format 标签生成schema, 返回nil表示不是时间字段.
+// 类型映射(RegisterTypeMapping)优先, 内置的 time.Duration 映射只用于json
+//
+//	time.Time                       => {type: string, format: date-time}
+//	time_format:"unix"              => {type: integer, format: int64}  (unixmilli/unixmicro/unixnano同理)
+//	time_format:"2006-01-02"        => {type: string, format: date}
+//	time_format:"15:04:05"          => {type: string, format: time}
+//	time_format:"2006/01/02 15:04"  => {type: string, pattern: ^\d{4}/\d{2}/\d{2} \d{2}:\d{2}$}
+//	time.Duration                   => 表单/查询参数中为 1h30m 这样的字符串, json中为纳秒数(内置的类型映射)
+func (oa *OpenAPI) timeFieldSchema(f *types.Field, tagName string, defaultValue string, comment string, exampleValue string) *spec.RefOrSpec[spec.Schema] {
+	var builder *spec.SchemaBuilder
+	switch {
+	case isTimeType(f):
+		builder = timeLayoutSchema(f.GetTag().Get("time_format"))
+	case isDurationType(f):
+		builder = spec.NewSchemaBuilder().Type("string").
+			Pattern(`^-?([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$`).
+			Example((90 * time.Minute).String())
+	default:
+		return nil
+	}
+	schema := builder.Description(comment).Build()
+	typ := schemaType(schema.Spec)
+	if defaultValue != "" {
+		schema.Spec.Default = typedValue(typ, defaultValue)
+	}
+	if exampleValue != "" {
+		schema.Spec.Example = typedValue(typ, exampleValue)
+	}
+	if f.Slice || strings.HasPrefix(f.Type, "[]") {
+		return spec.NewSchemaBuilder().Type("array").Items(spec.NewBoolOrSchema(schema)).Description(comment).Build()
+	}
+	return schema
+}
+
+// timeLayoutSchema time_format 对应的schema及示例
+func timeLayoutSchema(layout string) *spec.SchemaBuilder {
+	switch strings.ToLower(layout) {
+	case "unix":
+		return spec.NewSchemaBuilder().Type("integer").Format("int64").Example(exampleTime.Unix())
+	case "unixmilli":
+		return spec.NewSchemaBuilder().Type("integer").Format("int64").Example(exampleTime.UnixMilli())
+	case "unixmicro":
+		return spec.NewSchemaBuilder().Type("integer").Format("int64").Example(exampleTime.UnixMicro())
+	case "unixnano":
+		return spec.NewSchemaBuilder().Type("integer").Format("int64").Example(exampleTime.UnixNano())
+	}
+	if l, ok := namedLayouts[layout]; ok {
+		layout = l
+	}
+	switch layout {
+	case "", time.RFC3339, time.RFC3339Nano:
+		return spec.NewSchemaBuilder().Type("string").Format("date-time").Example(exampleTime.Format(time.RFC3339))
+	case time.DateOnly:
+		return spec.NewSchemaBuilder().Type("string").Format("date").Example(exampleTime.Format(time.DateOnly))
+	case time.TimeOnly:
+		return spec.NewSchemaBuilder().Type("string").Format("time").Example(exampleTime.Format(time.TimeOnly))
+	}
+	return spec.NewSchemaBuilder().Type("string").Pattern(layoutPattern(layout)).Example(exampleTime.Format(layout))
+}
+
+// layoutTokens Go时间布局中的元素及对应的正则, 按长度优先匹配
+var layoutTokens = []struct {
+	token   string
+	pattern string
+}{
+	{"January", `[A-Z][a-z]+`},
+	{"Monday", `[A-Z][a-z]+`},
+	{"Z07:00:00", `(Z|[+-][0-9]{2}:[0-9]{2}:[0-9]{2})`},
+	{"-07:00:00", `[+-][0-9]{2}:[0-9]{2}:[0-9]{2}`},
+	{"Z07:00", `(Z|[+-][0-9]{2}:[0-9]{2})`},
+	{"-07:00", `[+-][0-9]{2}:[0-9]{2}`},
+	{"Z0700", `(Z|[+-][0-9]{4})`},
+	{"-0700", `[+-][0-9]{4}`},
+	{"Z07", `(Z|[+-][0-9]{2})`},
+	{"-07", `[+-][0-9]{2}`},
+	{"2006", `[0-9]{4}`},
+	{"Jan", `[A-Z][a-z]{2}`},
+	{"Mon", `[A-Z][a-z]{2}`},
+	{"MST", `[A-Z]{3,5}`},
+	{"002", `[0-9]{3}`},
+	{"__2", `[ 0-9]{2}[0-9]`},
+	{"_2", `[ 0-9][0-9]`},
+	{"01", `[0-9]{2}`},
+	{"02", `[0-9]{2}`},
+	{"03", `[0-9]{2}`},
+	{"04", `[0-9]{2}`},
+	{"05", `[0-9]{2}`},
+	{"06", `[0-9]{2}`},
+	{"15", `[0-9]{2}`},
+	{"PM", `(AM|PM)`},
+	{"pm", `(am|pm)`},
+	{"1", `[0-9]{1,2}`},
+	{"2", `[0-9]{1,2}`},
+	{"3", `[0-9]{1,2}`},
+	{"4", `[0-9]{1,2}`},
+	{"5", `[0-9]{1,2}`},
+}
+
+// fractionalSeconds 秒的小数部分 .000 / .999, 与time包一致, 后面不能紧跟数字
+var fractionalSeconds = regexp.MustCompile(`^[.,](0+|9+)([^0-9]|$)`)
+
+// layoutPattern Go时间布局转换为完整匹配的正则
+func layoutPattern(layout string) string {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(layout); {
+		if sub := fractionalSeconds.FindStringSubmatch(layout[i:]); sub != nil {
+			m := layout[i : i+1+len(sub[1])]
+			if m[1] == '0' {
+				b.WriteString(regexp.QuoteMeta(m[:1]) + "[0-9]{" + conv.String(len(m)-1) + "}")
+			} else {
+				b.WriteString("(" + regexp.QuoteMeta(m[:1]) + "[0-9]+)?")
+			}
+			i += len(m)
+			continue
+		}
+		matched := false
+		for _, t := range layoutTokens {
+			if strings.HasPrefix(layout[i:], t.token) {
+				b.WriteString(t.pattern)
+				i += len(t.token)
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			b.WriteString(regexp.QuoteMeta(layout[i : i+1]))
+			i++
+		}
+	}
+	b.WriteString("$")
+	return b.String()
+}
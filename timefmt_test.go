@@ -0,0 +1,117 @@
+package fw_openapi
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/linxlib/astp/types"
+)
+
+func TestLayoutPattern(t *testing.T) {
+	tests := []struct {
+		name    string
+		layout  string
+		pattern string
+	}{
+		{
+			name:    "date with slashes",
+			layout:  "2006/01/02",
+			pattern: `^[0-9]{4}/[0-9]{2}/[0-9]{2}$`,
+		},
+		{
+			name:    "date and time",
+			layout:  "2006/01/02 15:04",
+			pattern: `^[0-9]{4}/[0-9]{2}/[0-9]{2} [0-9]{2}:[0-9]{2}$`,
+		},
+		{
+			name:    "dots between date digits are not fractional seconds",
+			layout:  "2006.01.02",
+			pattern: `^[0-9]{4}\.[0-9]{2}\.[0-9]{2}$`,
+		},
+		{
+			name:    "fixed fractional seconds",
+			layout:  "15:04:05.000",
+			pattern: `^[0-9]{2}:[0-9]{2}:[0-9]{2}\.[0-9]{3}$`,
+		},
+		{
+			name:    "optional fractional seconds",
+			layout:  "15:04:05.999999",
+			pattern: `^[0-9]{2}:[0-9]{2}:[0-9]{2}(\.[0-9]+)?$`,
+		},
+		{
+			name:    "comma fractional seconds before zone",
+			layout:  "2006-01-02T15:04:05,000Z07:00",
+			pattern: `^[0-9]{4}-[0-9]{2}-[0-9]{2}T[0-9]{2}:[0-9]{2}:[0-9]{2},[0-9]{3}(Z|[+-][0-9]{2}:[0-9]{2})$`,
+		},
+		{
+			name:    "month and day names",
+			layout:  "Mon, 02 Jan 2006",
+			pattern: `^[A-Z][a-z]{2}, [0-9]{2} [A-Z][a-z]{2} [0-9]{4}$`,
+		},
+		{
+			name:    "12-hour clock",
+			layout:  "3:04PM",
+			pattern: `^[0-9]{1,2}:[0-9]{2}(AM|PM)$`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := layoutPattern(tt.layout)
+			if got != tt.pattern {
+				t.Errorf("layoutPattern(%q) = %s, want %s", tt.layout, got, tt.pattern)
+			}
+			// 按布局格式化的时间应匹配生成的正则
+			value := exampleTime.Add(123456 * time.Microsecond).Format(tt.layout)
+			if !regexp.MustCompile(got).MatchString(value) {
+				t.Errorf("layoutPattern(%q) = %s does not match %q", tt.layout, got, value)
+			}
+		})
+	}
+}
+
+func TestIsTimePackageType(t *testing.T) {
+	tests := []struct {
+		name     string
+		field    *types.Field
+		time     bool
+		duration bool
+	}{
+		{name: "short time", field: &types.Field{Type: "Time"}, time: true},
+		{name: "pointer to short time", field: &types.Field{Type: "*Time"}, time: true},
+		{name: "qualified time", field: &types.Field{Type: "time.Time"}, time: true},
+		{name: "slice of time", field: &types.Field{Type: "[]time.Time", Slice: true}, time: true},
+		{name: "short duration", field: &types.Field{Type: "Duration"}, duration: true},
+		{name: "qualified duration", field: &types.Field{Type: "*time.Duration"}, duration: true},
+		{
+			name:  "resolved time",
+			field: &types.Field{Type: "Time", Struct: &types.Struct{Name: "Time", TypeName: "Time", PackagePath: "time"}},
+			time:  true,
+		},
+		{
+			name:     "resolved duration",
+			field:    &types.Field{Type: "Duration", Struct: &types.Struct{Name: "Duration", TypeName: "Duration", PackagePath: "time"}},
+			duration: true,
+		},
+		{
+			name:  "time of another package",
+			field: &types.Field{Type: "Time", Struct: &types.Struct{Name: "Time", TypeName: "Time", PackagePath: "example.com/clock"}},
+		},
+		{
+			name:  "duration of another package",
+			field: &types.Field{Type: "Duration", Struct: &types.Struct{Name: "Duration", TypeName: "Duration", PackagePath: "example.com/clock"}},
+		},
+		{name: "string", field: &types.Field{Type: "string"}},
+		{name: "other type named like time", field: &types.Field{Type: "Timestamp"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isTimeType(tt.field); got != tt.time {
+				t.Errorf("isTimeType(%q) = %v, want %v", tt.field.Type, got, tt.time)
+			}
+			if got := isDurationType(tt.field); got != tt.duration {
+				t.Errorf("isDurationType(%q) = %v, want %v", tt.field.Type, got, tt.duration)
+			}
+		})
+	}
+}
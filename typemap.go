@@ -0,0 +1,191 @@
+package fw_openapi
+
+import (
+	"encoding/json"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/linxlib/astp/types"
+	spec "github.com/sv-tools/openapi"
+)
+
+// typeMappings 按Go类型指定的schema, 同时按 完整路径.类型 / 包名.类型 / 类型 索引
+type typeMappings struct {
+	full      map[string]*spec.RefOrSpec[spec.Schema]
+	qualified map[string]*spec.RefOrSpec[spec.Schema]
+	short     map[string]*spec.RefOrSpec[spec.Schema]
+	builtin   map[string]bool // 未被覆盖的内置映射
+}
+
+// RegisterTypeMapping
+// 指定Go类型对应的schema, 优先于结构体解析, 可覆盖内置的映射
+//
+//	oa.RegisterTypeMapping("github.com/google/uuid.UUID", spec.NewSchemaBuilder().Type("string").Format("uuid").Build())
+//	oa.RegisterTypeMapping("github.com/example/money.Amount", spec.NewSchemaBuilder().Type("string").Pattern(`^-?\d+(\.\d{1,2})?$`).Build())
+func (oa *OpenAPI) RegisterTypeMapping(goType string, schema *spec.RefOrSpec[spec.Schema]) {
+	if oa.mappings == nil {
+		oa.mappings = defaultTypeMappings()
+	}
+	oa.mappings.add(goType, schema)
+}
+
+func (m *typeMappings) add(goType string, schema *spec.RefOrSpec[spec.Schema]) {
+	goType = strings.TrimLeft(strings.TrimSpace(goType), "*")
+	m.full[goType] = schema
+	delete(m.builtin, goType)
+	pkg, name := splitGoType(goType)
+	if pkg == "" {
+		m.short[name] = schema
+		return
+	}
+	m.qualified[path.Base(pkg)+"."+name] = schema
+	m.short[name] = schema
+}
+
+// splitGoType github.com/google/uuid.UUID => github.com/google/uuid, UUID
+func splitGoType(goType string) (string, string) {
+	i := strings.LastIndex(goType, ".")
+	if i < 0 || strings.HasPrefix(goType, "[]") {
+		return "", goType
+	}
+	return goType[:i], goType[i+1:]
+}
+
+// typeMapping
+// 查找字段类型对应的schema: 解析到结构体时按其包路径匹配,
+// 否则按源码中的写法(uuid.UUID 或 UUID)匹配, 返回的schema是副本, 可以修改
+func (oa *OpenAPI) typeMapping(goType string, s *types.Struct) *spec.RefOrSpec[spec.Schema] {
+	if oa.mappings == nil {
+		oa.mappings = defaultTypeMappings()
+	}
+	goType = strings.TrimLeft(strings.TrimSpace(goType), "*")
+	if s != nil && structPackage(s) != "" {
+		name := strings.TrimLeft(s.TypeName, "*")
+		if i := strings.LastIndex(name, "."); i >= 0 {
+			name = name[i+1:]
+		}
+		if schema, ok := oa.mappings.full[structPackage(s)+"."+name]; ok {
+			return cloneSchema(schema)
+		}
+		// 项目中的结构体不按短名称匹配, 避免与内置映射同名的结构体被替换
+		return nil
+	}
+	if schema, ok := oa.mappings.full[goType]; ok {
+		return cloneSchema(schema)
+	}
+	// sql.Null[T] 为T可为null
+	if base, arg, ok := strings.Cut(goType, "["); ok && (base == "sql.Null" || base == "Null") {
+		if b := primitiveSchema(strings.TrimSuffix(arg, "]")); b != nil {
+			return nullable(b.Build())
+		}
+	}
+	if strings.Contains(goType, ".") {
+		if schema, ok := oa.mappings.qualified[goType]; ok {
+			return cloneSchema(schema)
+		}
+		return nil
+	}
+	if schema, ok := oa.mappings.short[goType]; ok {
+		return cloneSchema(schema)
+	}
+	return nil
+}
+
+// cloneSchema 映射的schema在每次使用时复制, 避免修改(如description/nullable)影响其他字段
+func cloneSchema(schema *spec.RefOrSpec[spec.Schema]) *spec.RefOrSpec[spec.Schema] {
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return schema
+	}
+	clone := new(spec.RefOrSpec[spec.Schema])
+	if err := json.Unmarshal(data, clone); err != nil {
+		return schema
+	}
+	return clone
+}
+
+// defaultTypeMappings 内置的常用类型映射
+func defaultTypeMappings() *typeMappings {
+	m := &typeMappings{
+		full:      make(map[string]*spec.RefOrSpec[spec.Schema]),
+		qualified: make(map[string]*spec.RefOrSpec[spec.Schema]),
+		short:     make(map[string]*spec.RefOrSpec[spec.Schema]),
+	}
+	uuid := func() *spec.RefOrSpec[spec.Schema] {
+		return spec.NewSchemaBuilder().Type("string").Format("uuid").Example("3fa85f64-5717-4562-b3fc-2c963f66afa6").Build()
+	}
+	m.add("github.com/google/uuid.UUID", uuid())
+	m.add("github.com/gofrs/uuid.UUID", uuid())
+	m.add("github.com/satori/go.uuid.UUID", uuid())
+	m.add("time.Duration", spec.NewSchemaBuilder().Type("integer").Format("int64").Description("nanoseconds").Example(int64(90*time.Minute)).Build())
+	m.add("net.IP", spec.NewSchemaBuilder().Type("string").AnyOf(
+		spec.NewSchemaBuilder().Format("ipv4").Build(),
+		spec.NewSchemaBuilder().Format("ipv6").Build(),
+	).Build())
+	m.add("net/url.URL", spec.NewSchemaBuilder().Type("string").Format("uri").Build())
+	m.add("encoding/json.RawMessage", spec.NewSchemaBuilder().Build())
+	// []byte 被encoding/json编码为base64字符串
+	m.add("[]byte", spec.NewSchemaBuilder().Type("string").Format("byte").ContentEncoding(spec.Base64Encoding).Build())
+	m.add("[]uint8", spec.NewSchemaBuilder().Type("string").Format("byte").ContentEncoding(spec.Base64Encoding).Build())
+	for name, goType := range map[string]string{
+		"NullString":  "string",
+		"NullBool":    "bool",
+		"NullByte":    "uint8",
+		"NullInt16":   "int16",
+		"NullInt32":   "int32",
+		"NullInt64":   "int64",
+		"NullFloat64": "float64",
+	} {
+		m.add("database/sql."+name, nullable(primitiveSchema(goType).Build()))
+	}
+	m.add("database/sql.NullTime", spec.NewSchemaBuilder().Type("string", "null").Format("date-time").Build())
+	// big.Int 编码为不限长度的数字, big.Float / big.Rat 编码为字符串
+	m.add("math/big.Int", spec.NewSchemaBuilder().Type("integer").Build())
+	m.add("math/big.Float", spec.NewSchemaBuilder().Type("string").Pattern(`^-?[0-9]+(\.[0-9]+)?([eE][-+]?[0-9]+)?$`).Build())
+	m.add("math/big.Rat", spec.NewSchemaBuilder().Type("string").Pattern(`^-?[0-9]+(/[0-9]+)?$`).Build())
+	m.add("github.com/shopspring/decimal.Decimal", spec.NewSchemaBuilder().Type("number").Format("double").Build())
+	m.builtin = make(map[string]bool, len(m.full))
+	for goType := range m.full {
+		m.builtin[goType] = true
+	}
+	return m
+}
+
+// mappedFieldSchema 字段类型在映射中时使用映射的schema, 补充字段的注释、默认值及示例
+func (oa *OpenAPI) mappedFieldSchema(f *types.Field, tagName string, defaultValue string, comment string, exampleValue string) *spec.RefOrSpec[spec.Schema] {
+	if oa.mappings == nil {
+		oa.mappings = defaultTypeMappings()
+	}
+	// 表单/查询参数中的 time.Duration 按 1h30m 这样的字符串绑定, 内置的纳秒数映射不适用, 由 timeFieldSchema 处理
+	if tagName != "json" && isDurationType(f) && oa.mappings.builtin["time.Duration"] {
+		return nil
+	}
+	goType := f.Type
+	slice := f.Slice
+	elem := strings.TrimLeft(strings.TrimPrefix(goType, "[]"), "*")
+	// []byte 整体映射为base64字符串
+	if (slice || strings.HasPrefix(goType, "[]")) && (elem == "byte" || elem == "uint8") {
+		goType, slice = "[]byte", false
+	} else {
+		goType = elem
+		slice = slice || strings.HasPrefix(f.Type, "[]")
+	}
+	schema := oa.typeMapping(goType, f.Struct)
+	if schema == nil {
+		return nil
+	}
+	if schema.Spec != nil {
+		if comment != "" {
+			schema.Spec.Description = comment
+		}
+		if defaultValue != "" {
+			schema.Spec.Default = typedValue(schemaType(schema.Spec), defaultValue)
+		}
+	}
+	schema = withExample(schema, exampleValue)
+	if slice {
+		return spec.NewSchemaBuilder().Type("array").Items(spec.NewBoolOrSchema(schema)).Description(comment).Build()
+	}
+	return schema
+}
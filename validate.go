@@ -0,0 +1,185 @@
+package fw_openapi
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/linxlib/conv"
+	spec "github.com/sv-tools/openapi"
+)
+
+// validateRule 字段上的一条校验规则, 如 min:1 => {name: "min", args: ["1"]}
+type validateRule struct {
+	name string
+	args []string
+}
+
+// parseValidateRules
+// 解析字段的 validate(gookit/validate) 及 binding(go-playground) 标签
+//
+//	validate:"required|min:1|in:a,b,c"
+//	binding:"required,min=1,oneof=a b c"
+func parseValidateRules(tag reflect.StructTag) []validateRule {
+	rules := make([]validateRule, 0)
+	if v := tag.Get("validate"); v != "" {
+		rules = append(rules, splitValidateRules(v)...)
+	}
+	if v := tag.Get("binding"); v != "" {
+		rules = append(rules, splitValidateRules(v)...)
+	}
+	return rules
+}
+
+// regexRuleStart 正则规则的开始, 其参数中可能包含 | , 等分隔符, 之后的内容都作为正则
+var regexRuleStart = regexp.MustCompile(`(?i)(^|[|,])\s*(regexp|regex|pattern)([:=])`)
+
+func splitValidateRules(v string) []validateRule {
+	rules := make([]validateRule, 0)
+	head, tail, tailSep := v, "", ""
+	if m := regexRuleStart.FindStringSubmatchIndex(v); m != nil {
+		head, tail, tailSep = v[:m[0]], v[m[4]:], v[m[6]:m[7]]
+	}
+	// gookit/validate 使用 | 分隔规则, : 分隔参数, 参数列表用 , 分隔
+	// go-playground 使用 , 分隔规则, = 分隔参数, 参数列表用空格分隔
+	ruleSep, argSep, listSep := ",", "=", " "
+	if strings.Contains(head, "|") || (strings.Contains(head, ":") && !strings.Contains(head, "=")) || tailSep == ":" {
+		ruleSep, argSep, listSep = "|", ":", ","
+	}
+	for _, s := range strings.Split(head, ruleSep) {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		// go-playground dive 之后的规则作用于元素
+		if s == "dive" {
+			return rules
+		}
+		name, arg, _ := strings.Cut(s, argSep)
+		rule := validateRule{name: strings.ToLower(name)}
+		if arg != "" {
+			rule.args = strings.Split(arg, listSep)
+		}
+		rules = append(rules, rule)
+	}
+	if tail != "" {
+		name, arg, _ := strings.Cut(tail, tailSep)
+		rules = append(rules, validateRule{name: strings.ToLower(name), args: []string{arg}})
+	}
+	return rules
+}
+
+// hasRequiredRule 校验规则中是否包含required
+func hasRequiredRule(rules []validateRule) bool {
+	for _, rule := range rules {
+		if rule.name == "required" {
+			return true
+		}
+	}
+	return false
+}
+
+// applyValidateRules
+// 将校验规则转换为对应的JSON Schema关键字, $ref 无法附加约束会被跳过
+func applyValidateRules(schema *spec.RefOrSpec[spec.Schema], rules []validateRule) {
+	if schema == nil || schema.Spec == nil || len(rules) == 0 {
+		return
+	}
+	s := schema.Spec
+	typ := schemaType(s)
+	for _, rule := range rules {
+		arg := ""
+		if len(rule.args) > 0 {
+			arg = strings.TrimSpace(rule.args[0])
+		}
+		switch rule.name {
+		case "min", "gte":
+			setLowerBound(s, typ, conv.Int(arg))
+		case "max", "lte":
+			setUpperBound(s, typ, conv.Int(arg))
+		case "gt":
+			if typ == "integer" || typ == "number" {
+				s.ExclusiveMinimum = intPtr(conv.Int(arg))
+			}
+		case "lt":
+			if typ == "integer" || typ == "number" {
+				s.ExclusiveMaximum = intPtr(conv.Int(arg))
+			}
+		case "len":
+			setLowerBound(s, typ, conv.Int(arg))
+			setUpperBound(s, typ, conv.Int(arg))
+		case "minlen", "min_len", "minlength":
+			s.MinLength = intPtr(conv.Int(arg))
+		case "maxlen", "max_len", "maxlength":
+			s.MaxLength = intPtr(conv.Int(arg))
+		case "regexp", "regex", "pattern":
+			s.Pattern = arg
+		case "email":
+			s.Format = "email"
+		case "url", "fullurl", "uri":
+			s.Format = "uri"
+		case "uuid", "uuid4":
+			s.Format = "uuid"
+		case "in", "enum", "oneof":
+			s.Enum = make([]any, 0, len(rule.args))
+			for _, a := range rule.args {
+				a = strings.TrimSpace(a)
+				if a == "" {
+					continue
+				}
+				switch typ {
+				case "integer":
+					s.Enum = append(s.Enum, conv.Int(a))
+				case "number":
+					s.Enum = append(s.Enum, conv.Float64(a))
+				default:
+					s.Enum = append(s.Enum, a)
+				}
+			}
+		}
+	}
+}
+
+// setLowerBound min/len 对数字是取值范围, 对字符串是长度, 对数组是元素个数
+func setLowerBound(s *spec.Schema, typ string, v int) {
+	switch typ {
+	case "string":
+		s.MinLength = intPtr(v)
+	case "array":
+		s.MinItems = intPtr(v)
+	case "object":
+		s.MinProperties = intPtr(v)
+	default:
+		s.Minimum = intPtr(v)
+	}
+}
+
+func setUpperBound(s *spec.Schema, typ string, v int) {
+	switch typ {
+	case "string":
+		s.MaxLength = intPtr(v)
+	case "array":
+		s.MaxItems = intPtr(v)
+	case "object":
+		s.MaxProperties = intPtr(v)
+	default:
+		s.Maximum = intPtr(v)
+	}
+}
+
+// schemaType 返回schema的第一个非null类型
+func schemaType(s *spec.Schema) string {
+	if s.Type == nil {
+		return ""
+	}
+	for _, t := range *s.Type {
+		if t != "null" {
+			return t
+		}
+	}
+	return ""
+}
+
+func intPtr(v int) *int {
+	return &v
+}
@@ -0,0 +1,83 @@
+package fw_openapi
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitValidateRules(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  []validateRule
+	}{
+		{
+			name:  "gookit rules",
+			value: "required|min:1|in:a,b,c",
+			want: []validateRule{
+				{name: "required"},
+				{name: "min", args: []string{"1"}},
+				{name: "in", args: []string{"a", "b", "c"}},
+			},
+		},
+		{
+			name:  "gookit single rule with argument",
+			value: "max:10",
+			want:  []validateRule{{name: "max", args: []string{"10"}}},
+		},
+		{
+			name:  "go-playground rules",
+			value: "required,min=1,oneof=a b c",
+			want: []validateRule{
+				{name: "required"},
+				{name: "min", args: []string{"1"}},
+				{name: "oneof", args: []string{"a", "b", "c"}},
+			},
+		},
+		{
+			name:  "go-playground rules stop at dive",
+			value: "required,max=3,dive,min=1",
+			want: []validateRule{
+				{name: "required"},
+				{name: "max", args: []string{"3"}},
+			},
+		},
+		{
+			name:  "gookit regex with alternation",
+			value: "required|regex:^(foo|bar)$",
+			want: []validateRule{
+				{name: "required"},
+				{name: "regex", args: []string{"^(foo|bar)$"}},
+			},
+		},
+		{
+			name:  "go-playground regexp with alternation",
+			value: "required,regexp=^(a|b)[0-9]{1,3}$",
+			want: []validateRule{
+				{name: "required"},
+				{name: "regexp", args: []string{"^(a|b)[0-9]{1,3}$"}},
+			},
+		},
+		{
+			name:  "regex only",
+			value: "pattern:^[a-z]+(,[a-z]+)*$",
+			want:  []validateRule{{name: "pattern", args: []string{"^[a-z]+(,[a-z]+)*$"}}},
+		},
+		{
+			name:  "rule names are lowercased",
+			value: "Required|MinLen:2",
+			want: []validateRule{
+				{name: "required"},
+				{name: "minlen", args: []string{"2"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := splitValidateRules(tt.value)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitValidateRules(%q) = %+v, want %+v", tt.value, got, tt.want)
+			}
+		})
+	}
+}